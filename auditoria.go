@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Operações registradas na tabela de auditoria.
+const (
+	operacaoInsercao    = "insert"
+	operacaoAtualizacao = "update"
+	operacaoRemocao     = "delete"
+)
+
+// RegistroAuditoria é uma alteração no histórico de uma pessoa. ValorAnterior
+// fica ausente nas inserções e ValorNovo nas remoções.
+type RegistroAuditoria struct {
+	ID            int64           `json:"id"`
+	PessoaID      int             `json:"pessoa_id"`
+	Operacao      string          `json:"operacao"`
+	ValorAnterior json.RawMessage `json:"valor_anterior,omitempty"`
+	ValorNovo     json.RawMessage `json:"valor_novo,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`
+	RegistradoEm  time.Time       `json:"registrado_em"`
+}
+
+// registrarAuditoria grava a alteração na mesma transação da mutação, para que
+// o histórico nunca divirja dos dados.
+func registrarAuditoria(ctx context.Context, tx *sql.Tx, operacao string, pessoaID int, anterior, novo *Pessoa) error {
+	valorAnterior, err := jsonOuNulo(anterior)
+	if err != nil {
+		return err
+	}
+	valorNovo, err := jsonOuNulo(novo)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlPessoas(`INSERT INTO {auditoria}
+		(pessoa_id, operacao, valor_anterior, valor_novo, request_id) VALUES (?, ?, ?, ?, ?)`),
+		pessoaID, operacao, valorAnterior, valorNovo, nuloSeVazio(requestID(ctx)))
+	return err
+}
+
+// registrarAuditoriaInsercoes audita as inserções das pessoas com os uuids
+// informados com um único comando, lendo os valores gravados da própria tabela.
+func registrarAuditoriaInsercoes(ctx context.Context, tx *sql.Tx, uuids []string) error {
+	args := make([]any, 0, len(uuids)+2)
+	args = append(args, operacaoInsercao, nuloSeVazio(requestID(ctx)))
+	for _, u := range uuids {
+		args = append(args, u)
+	}
+	_, err := tx.ExecContext(ctx, sqlPessoas(`INSERT INTO {auditoria} (pessoa_id, operacao, valor_novo, request_id)
+		SELECT id, ?, JSON_OBJECT('id', id, 'uuid', uuid, 'nome', nome, 'criado_em', DATE_FORMAT(criado_em, '%Y-%m-%dT%H:%i:%sZ')), ?
+		FROM {tabela} WHERE uuid IN (`+strings.TrimSuffix(strings.Repeat("?,", len(uuids)), ",")+`) ORDER BY id`),
+		args...)
+	return err
+}
+
+// jsonOuNulo codifica p, devolvendo nil (NULL no banco) quando p é nil.
+func jsonOuNulo(p *Pessoa) (any, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// nuloSeVazio converte a string vazia em NULL.
+func nuloSeVazio(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// historicoPessoa responde com o histórico de alterações de uma pessoa, inclusive
+// se ela já tiver sido removida.
+func historicoPessoa(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+
+	registros, err := repositorio.History(r.Context(), id)
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_historico", err)
+		return
+	}
+
+	// Sem histórico, distingue uma pessoa anterior à auditoria de um id que
+	// nunca existiu.
+	if len(registros) == 0 {
+		if _, err := repositorio.Get(r.Context(), id); err != nil {
+			if errors.Is(err, ErrPessoaNaoEncontrada) {
+				responderErro(w, r, http.StatusNotFound, mensagem(r, "pessoa_nao_encontrada"))
+			} else {
+				responderErroBanco(w, r, "erro_buscar_pessoa", err)
+			}
+			return
+		}
+	}
+
+	responderJSON(w, r, http.StatusOK, registros)
+}
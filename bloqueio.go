@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// termosBloqueados guarda, já dobrados por dobrarTermo, os trechos que não podem
+// aparecer em nomes. É trocado inteiro a cada recarga.
+var termosBloqueados atomic.Pointer[[]string]
+
+// dobrarTermo remove acentos e caixa de s, para que "JOSÉ" e "jose" se
+// equivalham na comparação com a lista de bloqueio.
+func dobrarTermo(s string) string {
+	// O transform.Chain guarda estado, então é criado a cada chamada.
+	semAcento := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	dobrado, _, err := transform.String(semAcento, s)
+	if err != nil {
+		dobrado = s
+	}
+	return strings.ToLower(dobrado)
+}
+
+// carregarBloqueioNomes monta a lista de bloqueio a partir de NAME_BLOCKLIST
+// (separada por vírgulas) e do arquivo NAME_BLOCKLIST_FILE (um termo por linha;
+// linhas vazias e iniciadas por # são ignoradas).
+func carregarBloqueioNomes() error {
+	var termos []string
+	for _, termo := range lerEnvLista("NAME_BLOCKLIST") {
+		termos = append(termos, dobrarTermo(termo))
+	}
+
+	if caminho := os.Getenv("NAME_BLOCKLIST_FILE"); caminho != "" {
+		arquivo, err := os.Open(caminho)
+		if err != nil {
+			return err
+		}
+		defer arquivo.Close()
+
+		leitor := bufio.NewScanner(arquivo)
+		for leitor.Scan() {
+			linha := strings.TrimSpace(leitor.Text())
+			if linha == "" || strings.HasPrefix(linha, "#") {
+				continue
+			}
+			termos = append(termos, dobrarTermo(linha))
+		}
+		if err := leitor.Err(); err != nil {
+			return err
+		}
+	}
+
+	termosBloqueados.Store(&termos)
+	return nil
+}
+
+// configurarBloqueioNomes carrega a lista de bloqueio e a recarrega a cada
+// SIGHUP, permitindo editar o arquivo sem reiniciar o servidor. Uma recarga com
+// erro mantém a lista anterior.
+func configurarBloqueioNomes() error {
+	if err := carregarBloqueioNomes(); err != nil {
+		return err
+	}
+
+	sinais := make(chan os.Signal, 1)
+	signal.Notify(sinais, syscall.SIGHUP)
+	go func() {
+		for range sinais {
+			if err := carregarBloqueioNomes(); err != nil {
+				log.Printf("Erro ao recarregar a lista de bloqueio de nomes: %v", err)
+				continue
+			}
+			log.Printf("Lista de bloqueio de nomes recarregada: %d termos", len(*termosBloqueados.Load()))
+		}
+	}()
+	return nil
+}
+
+// termoBloqueado devolve o termo da lista de bloqueio contido em nome, se houver.
+func termoBloqueado(nome string) (string, bool) {
+	termos := termosBloqueados.Load()
+	if termos == nil || len(*termos) == 0 {
+		return "", false
+	}
+
+	dobrado := dobrarTermo(nome)
+	for _, termo := range *termos {
+		if strings.Contains(dobrado, termo) {
+			return termo, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// itemCache é uma pessoa lida do banco com o instante em que deixa de valer.
+type itemCache struct {
+	pessoa Pessoa
+	expira time.Time
+}
+
+// cacheLeitura guarda as pessoas lidas recentemente por obterPessoa para que
+// ela ainda possa responder, marcando a resposta como desatualizada, quando o
+// banco estiver fora do ar. As escritas nunca passam por ele.
+type cacheLeitura struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	maximo  int
+	pessoas map[int]itemCache
+}
+
+// cachePessoas fica nil quando READ_CACHE_TTL não está definido.
+var cachePessoas *cacheLeitura
+
+// iniciarCache habilita o cache com a validade READ_CACHE_TTL e no máximo
+// READ_CACHE_MAX_ENTRIES pessoas.
+func iniciarCache() {
+	ttl := lerEnvDuracao("READ_CACHE_TTL", 0)
+	if ttl <= 0 {
+		return
+	}
+	cachePessoas = &cacheLeitura{
+		ttl:     ttl,
+		maximo:  lerEnvInt("READ_CACHE_MAX_ENTRIES", 10000),
+		pessoas: make(map[int]itemCache),
+	}
+}
+
+// guardar registra a versão atual da pessoa.
+func (c *cacheLeitura) guardar(p Pessoa) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	agora := time.Now()
+	if len(c.pessoas) >= c.maximo {
+		for id, item := range c.pessoas {
+			if agora.After(item.expira) {
+				delete(c.pessoas, id)
+			}
+		}
+		if len(c.pessoas) >= c.maximo {
+			return
+		}
+	}
+	c.pessoas[p.ID] = itemCache{pessoa: p, expira: agora.Add(c.ttl)}
+}
+
+// obter devolve a pessoa guardada se ela ainda estiver dentro da validade.
+func (c *cacheLeitura) obter(id int) (Pessoa, bool) {
+	if c == nil {
+		return Pessoa{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, ok := c.pessoas[id]
+	if !ok || time.Now().After(item.expira) {
+		return Pessoa{}, false
+	}
+	return item.pessoa, true
+}
+
+// remover descarta a pessoa, usado quando ela é removida do banco.
+func (c *cacheLeitura) remover(id int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.pessoas, id)
+	c.mu.Unlock()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+)
+
+// chavesCamelCase, ligado por JSON_KEY_CASE=camel, troca as chaves snake_case
+// das respostas (criado_em, request_id) por camelCase (criadoEm, requestId).
+// O padrão, JSON_KEY_CASE=snake, mantém as tags das structs. A troca vale para
+// todo JSON enviado pelo serviço: respostas, eventos, NDJSON e webhooks.
+var chavesCamelCase = lerCaixaChavesJSON()
+
+func lerCaixaChavesJSON() bool {
+	switch valor := lerEnv("JSON_KEY_CASE", "snake"); valor {
+	case "", "snake":
+		return false
+	case "camel":
+		return true
+	default:
+		log.Fatalf("Valor inválido para JSON_KEY_CASE: %q (use snake ou camel)", valor)
+		return false
+	}
+}
+
+// codificarJSON é o json.Marshal do serviço: aplica JSON_KEY_CASE ao resultado.
+func codificarJSON(v any) ([]byte, error) {
+	dados, err := json.Marshal(v)
+	if err != nil || !chavesCamelCase {
+		return dados, err
+	}
+	dados, err = camelizarChaves(dados)
+	return bytes.TrimSuffix(dados, []byte("\n")), err
+}
+
+// recodificarCamelCase troca no buffer as chaves do JSON por camelCase,
+// indentando o resultado quando pretty é verdadeiro.
+func recodificarCamelCase(buf *bytes.Buffer, pretty bool) error {
+	dados, err := camelizarChaves(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	buf.Reset()
+	if !pretty {
+		buf.Write(dados)
+		return nil
+	}
+	return json.Indent(buf, dados, "", "  ")
+}
+
+// camelCase converte uma chave snake_case; chaves sem "_" voltam inalteradas.
+func camelCase(chave string) string {
+	partes := strings.Split(chave, "_")
+	for i := 1; i < len(partes); i++ {
+		if partes[i] != "" {
+			partes[i] = strings.ToUpper(partes[i][:1]) + partes[i][1:]
+		}
+	}
+	return strings.Join(partes, "")
+}
+
+// nivelJSON é um objeto ou array aberto durante camelizarChaves.
+type nivelJSON struct {
+	objeto      bool
+	esperaChave bool
+	vazio       bool
+}
+
+// camelizarChaves reescreve as chaves de objeto de dados com camelCase,
+// preservando a ordem dos campos e os valores. A saída é compacta, com um
+// valor por linha, como a de json.Encoder.
+func camelizarChaves(dados []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(dados))
+	dec.UseNumber()
+
+	var saida bytes.Buffer
+	var pilha []nivelJSON
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return saida.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			saida.WriteByte(byte(d))
+			pilha = pilha[:len(pilha)-1]
+			fecharValor(&saida, pilha)
+			continue
+		}
+
+		if len(pilha) > 0 {
+			topo := &pilha[len(pilha)-1]
+			if !topo.vazio && (!topo.objeto || topo.esperaChave) {
+				saida.WriteByte(',')
+			}
+			topo.vazio = false
+			if topo.objeto && topo.esperaChave {
+				chave, _ := json.Marshal(camelCase(tok.(string)))
+				saida.Write(chave)
+				saida.WriteByte(':')
+				topo.esperaChave = false
+				continue
+			}
+		}
+
+		switch valor := tok.(type) {
+		case json.Delim:
+			saida.WriteByte(byte(valor))
+			pilha = append(pilha, nivelJSON{objeto: valor == '{', esperaChave: valor == '{', vazio: true})
+			continue
+		case json.Number:
+			saida.WriteString(valor.String())
+		default:
+			codificado, err := json.Marshal(valor)
+			if err != nil {
+				return nil, err
+			}
+			saida.Write(codificado)
+		}
+		fecharValor(&saida, pilha)
+	}
+}
+
+// fecharValor registra o fim de um valor: num objeto, o próximo token é uma
+// chave; fora de qualquer nível, o valor de nível superior termina a linha.
+func fecharValor(saida *bytes.Buffer, pilha []nivelJSON) {
+	if len(pilha) == 0 {
+		saida.WriteByte('\n')
+		return
+	}
+	if topo := &pilha[len(pilha)-1]; topo.objeto {
+		topo.esperaChave = true
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBancoSobrecarregado indica que a operação esperou demais por uma vaga no
+// limite de concorrência do banco. Os handlers respondem 503.
+var ErrBancoSobrecarregado = errors.New("banco de dados sobrecarregado")
+
+// repositorioLimitado envolve um PessoaRepository limitando quantas operações
+// rodam ao mesmo tempo. As excedentes esperam até espera por uma vaga e então
+// falham com ErrBancoSobrecarregado, o que dá contrapressão aos clientes antes
+// de o MySQL ficar sem conexões.
+type repositorioLimitado struct {
+	repo     PessoaRepository
+	semaforo chan struct{}
+	espera   time.Duration
+}
+
+// limitarConcorrencia aplica DB_MAX_CONCURRENCY e DB_QUEUE_TIMEOUT ao
+// repositório. Com DB_MAX_CONCURRENCY=0 o repositório é devolvido sem limite.
+func limitarConcorrencia(repo PessoaRepository) PessoaRepository {
+	maximo := lerEnvInt("DB_MAX_CONCURRENCY", 50)
+	if maximo <= 0 {
+		return repo
+	}
+	return &repositorioLimitado{
+		repo:     repo,
+		semaforo: make(chan struct{}, maximo),
+		espera:   lerEnvDuracao("DB_QUEUE_TIMEOUT", time.Second),
+	}
+}
+
+// adquirir reserva uma vaga, devolvendo a função que a libera.
+func (l *repositorioLimitado) adquirir(ctx context.Context) (func(), error) {
+	metricaFilaBanco.Add(1)
+	defer metricaFilaBanco.Add(-1)
+
+	timer := time.NewTimer(l.espera)
+	defer timer.Stop()
+
+	select {
+	case l.semaforo <- struct{}{}:
+		metricaUsoBanco.Add(1)
+		return func() {
+			metricaUsoBanco.Add(-1)
+			<-l.semaforo
+		}, nil
+	case <-timer.C:
+		return nil, ErrBancoSobrecarregado
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *repositorioLimitado) List(ctx context.Context, filtro FiltroPessoas) ([]Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.List(ctx, filtro)
+}
+
+func (l *repositorioLimitado) Count(ctx context.Context, filtro FiltroPessoas) (int, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer liberar()
+	return l.repo.Count(ctx, filtro)
+}
+
+func (l *repositorioLimitado) CollectionState(ctx context.Context) (EstadoColecao, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return EstadoColecao{}, err
+	}
+	defer liberar()
+	return l.repo.CollectionState(ctx)
+}
+
+func (l *repositorioLimitado) Get(ctx context.Context, id int) (Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer liberar()
+	return l.repo.Get(ctx, id)
+}
+
+func (l *repositorioLimitado) GetByUUID(ctx context.Context, id string) (Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer liberar()
+	return l.repo.GetByUUID(ctx, id)
+}
+
+func (l *repositorioLimitado) Exists(ctx context.Context, id int) (bool, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer liberar()
+	return l.repo.Exists(ctx, id)
+}
+
+func (l *repositorioLimitado) Create(ctx context.Context, p Pessoa) (Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer liberar()
+	return l.repo.Create(ctx, p)
+}
+
+func (l *repositorioLimitado) CreateIfAbsent(ctx context.Context, p Pessoa) (Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer liberar()
+	return l.repo.CreateIfAbsent(ctx, p)
+}
+
+func (l *repositorioLimitado) NameExists(ctx context.Context, nome string) (bool, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer liberar()
+	return l.repo.NameExists(ctx, nome)
+}
+
+func (l *repositorioLimitado) CreateMany(ctx context.Context, pessoas []Pessoa) ([]Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.CreateMany(ctx, pessoas)
+}
+
+func (l *repositorioLimitado) Update(ctx context.Context, p Pessoa) (Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer liberar()
+	return l.repo.Update(ctx, p)
+}
+
+func (l *repositorioLimitado) UpdateMany(ctx context.Context, pessoas []Pessoa, parcial bool) (map[int]Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.UpdateMany(ctx, pessoas, parcial)
+}
+
+func (l *repositorioLimitado) Delete(ctx context.Context, id int) (Pessoa, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer liberar()
+	return l.repo.Delete(ctx, id)
+}
+
+func (l *repositorioLimitado) Batch(ctx context.Context, operacoes []OperacaoLote) ([]Pessoa, int, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer liberar()
+	return l.repo.Batch(ctx, operacoes)
+}
+
+func (l *repositorioLimitado) History(ctx context.Context, id int) ([]RegistroAuditoria, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.History(ctx, id)
+}
+
+func (l *repositorioLimitado) Suggest(ctx context.Context, prefixo string, limite int) ([]Sugestao, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.Suggest(ctx, prefixo, limite)
+}
+
+func (l *repositorioLimitado) Initials(ctx context.Context) ([]Inicial, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.Initials(ctx)
+}
+
+func (l *repositorioLimitado) Duplicates(ctx context.Context) ([]Duplicado, error) {
+	liberar, err := l.adquirir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer liberar()
+	return l.repo.Duplicates(ctx)
+}
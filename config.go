@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lerEnv devolve o valor da variável de ambiente nome, ou padrao quando ela
+// não está definida.
+func lerEnv(nome, padrao string) string {
+	if valor, ok := os.LookupEnv(nome); ok {
+		return valor
+	}
+	return padrao
+}
+
+// lerEnvBool interpreta a variável de ambiente nome como booleano.
+func lerEnvBool(nome string, padrao bool) bool {
+	valor, ok := os.LookupEnv(nome)
+	if !ok || valor == "" {
+		return padrao
+	}
+	b, err := strconv.ParseBool(valor)
+	if err != nil {
+		log.Fatalf("Valor inválido para %s: %q", nome, valor)
+	}
+	return b
+}
+
+// lerEnvInt interpreta a variável de ambiente nome como inteiro.
+func lerEnvInt(nome string, padrao int) int {
+	valor, ok := os.LookupEnv(nome)
+	if !ok || valor == "" {
+		return padrao
+	}
+	n, err := strconv.Atoi(valor)
+	if err != nil {
+		log.Fatalf("Valor inválido para %s: %q", nome, valor)
+	}
+	return n
+}
+
+// lerEnvLista interpreta a variável de ambiente nome como uma lista separada
+// por vírgulas, descartando itens vazios.
+func lerEnvLista(nome string) []string {
+	var itens []string
+	for _, item := range strings.Split(os.Getenv(nome), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			itens = append(itens, item)
+		}
+	}
+	return itens
+}
+
+// lerEnvDuracao interpreta a variável de ambiente nome como time.Duration
+// ("500ms", "2s", ...).
+func lerEnvDuracao(nome string, padrao time.Duration) time.Duration {
+	valor, ok := os.LookupEnv(nome)
+	if !ok || valor == "" {
+		return padrao
+	}
+	d, err := time.ParseDuration(valor)
+	if err != nil {
+		log.Fatalf("Valor inválido para %s: %q", nome, valor)
+	}
+	return d
+}
+
+// ocultarURL reduz uma URL ao esquema e ao host, descartando credenciais,
+// caminho e query, que podem carregar tokens.
+func ocultarURL(valor string) string {
+	if valor == "" {
+		return ""
+	}
+	u, err := url.Parse(valor)
+	if err != nil || u.Host == "" {
+		return "[oculto]"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// registrarResumoConfiguracao escreve no log, em formato chave=valor, a
+// configuração efetiva do servidor, para que se descubra pelos logs por que um
+// recurso está ligado ou desligado. Senhas e tokens nunca entram no resumo.
+func registrarResumoConfiguracao(endereco string) {
+	slog.Info("configuração",
+		"versao", versao,
+		"listen_addr", endereco,
+		"db_host", lerEnv("DB_HOST", "localhost:3306"),
+		"db_name", lerEnv("DB_NAME", "jean"),
+		"db_user", lerEnv("DB_USER", "username"),
+		"db_replica_leitura", lerEnv("DB_READ_DSN", "") != "",
+		"db_max_concurrency", lerEnvInt("DB_MAX_CONCURRENCY", 50),
+		"db_queue_timeout", lerEnvDuracao("DB_QUEUE_TIMEOUT", time.Second),
+		"db_healthcheck_interval", lerEnvDuracao("DB_HEALTHCHECK_INTERVAL", 0),
+		"tabela", tabelaPessoas,
+		"tabela_auditoria", tabelaAuditoria,
+		"auto_migrate", lerEnvBool("AUTO_MIGRATE", true),
+		"cors_origens", lerEnvLista("CORS_ALLOWED_ORIGINS"),
+		"max_body_bytes", lerEnvInt("MAX_BODY_BYTES", 10<<20),
+		"request_timeout", lerEnvDuracao("REQUEST_TIMEOUT", 10*time.Second),
+		"request_timeout_rotas", len(lerTimeoutsRotas()),
+		"rate_limit", lerEnvInt("RATE_LIMIT", 0),
+		"rate_limit_rotas", len(lerLimitesRotas()),
+		"max_concurrent_per_ip", lerEnvInt("MAX_CONCURRENT_PER_IP", 20),
+		"force_https", lerEnvBool("FORCE_HTTPS", false),
+		"cache_leitura", cachePessoas != nil,
+		"guarda_submissoes", guardaNomes != nil,
+		"webhook", ocultarURL(lerEnv("WEBHOOK_URL", "")),
+		"max_list_results", limiteResultados,
+		"max_bulk_size", maxItensLote,
+		"max_concurrent_bulk", cap(semaforoLote),
+		"bulk_queue_timeout", esperaLote,
+		"max_name_length", tamanhoMaximoNome,
+		"name_case", lerEnv("NAME_CASE", "none"),
+		"name_uniqueness", modoUnicidadeNome(),
+		"json_key_case", lerEnv("JSON_KEY_CASE", "snake"),
+		"list_etag", etagListas,
+		"expor_detalhes_erro", exporDetalhesErro,
+		"debug_db", lerEnvBool("ENABLE_DEBUG_DB", false),
+		"log_bodies", lerEnvBool("LOG_BODIES", false),
+		"pprof", lerEnvBool("ENABLE_PPROF", false),
+		"admin_shutdown", lerEnvBool("ENABLE_ADMIN_SHUTDOWN", false),
+		"root_route", !lerEnvBool("DISABLE_ROOT_ROUTE", false),
+		"admin_token", lerEnv("ADMIN_TOKEN", "") != "",
+		"read_only", modoLeitura.ativo.Load(),
+	)
+}
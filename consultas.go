@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tabelaPessoas é o nome da tabela de pessoas. Pode ser trocado por TABLE_NAME
+// para reaproveitar o serviço com um schema legado.
+var tabelaPessoas = "individuos"
+
+// tabelaAuditoria guarda o histórico de alterações; configurável por
+// AUDIT_TABLE_NAME.
+var tabelaAuditoria = "pessoas_audit"
+
+// tabelaVersao guarda, numa única linha, a versão de escrita das pessoas
+// usada pelo ETag das listagens. O nome deriva de tabelaPessoas.
+var tabelaVersao = tabelaPessoas + "_versao"
+
+// identificadorValido restringe nomes de tabela a identificadores simples, pois
+// eles são interpolados no SQL e não podem ser passados como parâmetro. O limite
+// de tamanho deixa espaço para os nomes de índice derivados da tabela dentro dos
+// 64 caracteres do MySQL.
+var identificadorValido = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,47}$`)
+
+// configurarTabela lê e valida TABLE_NAME e AUDIT_TABLE_NAME.
+func configurarTabela() error {
+	nome := lerEnv("TABLE_NAME", tabelaPessoas)
+	if !identificadorValido.MatchString(nome) {
+		return fmt.Errorf("TABLE_NAME inválido: %q", nome)
+	}
+	auditoria := lerEnv("AUDIT_TABLE_NAME", tabelaAuditoria)
+	if !identificadorValido.MatchString(auditoria) {
+		return fmt.Errorf("AUDIT_TABLE_NAME inválido: %q", auditoria)
+	}
+	tabelaPessoas, tabelaAuditoria, tabelaVersao = nome, auditoria, nome+"_versao"
+	return nil
+}
+
+// sqlPessoas monta a consulta substituindo {tabela}, {auditoria} e {versao}
+// pelos nomes configurados. Todas as consultas a essas tabelas devem passar por
+// aqui.
+func sqlPessoas(consulta string) string {
+	return strings.NewReplacer("{tabela}", tabelaPessoas, "{auditoria}", tabelaAuditoria, "{versao}", tabelaVersao).Replace(consulta)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"regexp"
+)
+
+// EstatisticasDB resume o estado do pool de conexões do banco de dados e os
+// contadores de metricas.go.
+type EstatisticasDB struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+
+	FilaBanco          int64 `json:"db_fila"`
+	EmUsoBanco         int64 `json:"db_em_uso"`
+	FilaLote           int64 `json:"lote_fila"`
+	EmUsoLote          int64 `json:"lote_em_uso"`
+	WebhookEntregues   int64 `json:"webhook_entregues"`
+	WebhookFalhas      int64 `json:"webhook_falhas"`
+	WebhookDescartados int64 `json:"webhook_descartados"`
+}
+
+// estatisticasDB responde com as estatísticas do pool de conexões, as filas
+// dos limites de DB_MAX_CONCURRENCY e MAX_CONCURRENT_BULK e os contadores de
+// entrega de webhooks. A rota só é registrada quando ENABLE_DEBUG_DB=true.
+func estatisticasDB(w http.ResponseWriter, r *http.Request) {
+	stats := dbConn.Stats()
+	responderJSON(w, r, http.StatusOK, EstatisticasDB{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration.String(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+
+		FilaBanco:          metricaFilaBanco.Load(),
+		EmUsoBanco:         metricaUsoBanco.Load(),
+		FilaLote:           metricaFilaLote.Load(),
+		EmUsoLote:          metricaUsoLote.Load(),
+		WebhookEntregues:   metricaWebhookEntregues.Load(),
+		WebhookFalhas:      metricaWebhookFalhas.Load(),
+		WebhookDescartados: metricaWebhookDescartados.Load(),
+	})
+}
+
+// iniciarPprof expõe os handlers de net/http/pprof em /debug/pprof quando
+// ENABLE_PPROF=true. Eles rodam num servidor separado, em PPROF_ADDR (por padrão
+// 127.0.0.1:6060), e nunca no roteador público: os perfis revelam detalhes
+// internos do processo e uma coleta de CPU custa desempenho, então a porta não
+// deve ser acessível fora da máquina ou da rede administrativa.
+func iniciarPprof() {
+	if !lerEnvBool("ENABLE_PPROF", false) {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	addr := lerEnv("PPROF_ADDR", "127.0.0.1:6060")
+	go func() {
+		log.Println("pprof disponível em", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Println("Erro ao iniciar o servidor pprof:", err)
+		}
+	}()
+}
+
+// camposSensiveis casa, em JSON e em formulários, os valores de campos que não
+// podem ir para o log.
+var camposSensiveis = []struct {
+	padrao     *regexp.Regexp
+	substituto string
+}{
+	{regexp.MustCompile(`(?i)("(?:senha|password|token|secret|authorization)"\s*:\s*)"(?:[^"\\]|\\.)*"?`), `$1"[oculto]"`},
+	{regexp.MustCompile(`(?i)((?:^|&)(?:senha|password|token|secret)=)[^&]*`), `${1}[oculto]`},
+}
+
+// ocultarSensiveis substitui no corpo os valores de camposSensiveis. Também
+// funciona num corpo truncado, em que o JSON não pode ser interpretado.
+func ocultarSensiveis(corpo []byte) []byte {
+	for _, c := range camposSensiveis {
+		corpo = c.padrao.ReplaceAll(corpo, []byte(c.substituto))
+	}
+	return corpo
+}
+
+// capturaLimitada guarda os primeiros limite bytes escritos nela.
+type capturaLimitada struct {
+	bytes.Buffer
+	limite   int
+	truncado bool
+}
+
+func (c *capturaLimitada) Write(p []byte) (int, error) {
+	if resto := c.limite - c.Len(); resto < len(p) {
+		c.truncado = true
+		p = p[:max(resto, 0)]
+	}
+	c.Buffer.Write(p)
+	return len(p), nil
+}
+
+func (c *capturaLimitada) texto() string {
+	if c.truncado {
+		return string(ocultarSensiveis(c.Bytes())) + "...(truncado)"
+	}
+	return string(ocultarSensiveis(c.Bytes()))
+}
+
+// respostaCapturada copia para uma capturaLimitada o corpo da resposta.
+type respostaCapturada struct {
+	http.ResponseWriter
+	status int
+	corpo  *capturaLimitada
+}
+
+func (w *respostaCapturada) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *respostaCapturada) Write(p []byte) (int, error) {
+	w.corpo.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *respostaCapturada) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// registrarCorposMiddleware, ligado por LOG_BODIES=true, escreve no log os
+// corpos de requisição e resposta dos métodos de escrita, para depurar
+// integrações em homologação. Cada corpo é limitado a LOG_BODIES_MAX_BYTES
+// (4 KiB) e tem senhas e tokens ocultos. O corpo da requisição é lido só até o
+// limite e recolocado à frente do restante, de modo que o handler o recebe
+// inteiro. Fica desligado por padrão: mesmo com a ocultação, nomes e demais
+// dados pessoais vão para o log.
+func registrarCorposMiddleware(next http.Handler) http.Handler {
+	if !lerEnvBool("LOG_BODIES", false) {
+		return next
+	}
+	limite := lerEnvInt("LOG_BODIES_MAX_BYTES", 4<<10)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requisicao := &capturaLimitada{limite: limite}
+		if r.Body != nil {
+			inicio, _ := io.ReadAll(io.LimitReader(r.Body, int64(limite)+1))
+			requisicao.Write(inicio)
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(inicio), r.Body), r.Body}
+		}
+
+		resposta := &respostaCapturada{ResponseWriter: w, status: http.StatusOK, corpo: &capturaLimitada{limite: limite}}
+		next.ServeHTTP(resposta, r)
+
+		log.Printf("[%s] %s %s corpo=%q -> %d corpo=%q", requestID(r.Context()), r.Method, caminhoOriginal(r),
+			requisicao.texto(), resposta.status, resposta.corpo.texto())
+	})
+}
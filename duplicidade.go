@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// guardaSubmissoes lembra os nomes criados recentemente para recusar o mesmo
+// cadastro enviado de novo logo em seguida, como num clique duplo. Complementa
+// a unicidade no banco, cobrindo também o caso em que ela não é exigida. Um
+// *guardaSubmissoes nil (o padrão) aceita tudo.
+type guardaSubmissoes struct {
+	mu     sync.Mutex
+	janela time.Duration
+	nomes  map[string]time.Time
+}
+
+// guardaNomes é a guarda usada por adicionarPessoa, configurada por
+// iniciarGuardaSubmissoes.
+var guardaNomes *guardaSubmissoes
+
+// iniciarGuardaSubmissoes ativa a guarda quando DUPLICATE_SUBMIT_WINDOW (por
+// exemplo "2s") é maior que zero.
+func iniciarGuardaSubmissoes() {
+	janela := lerEnvDuracao("DUPLICATE_SUBMIT_WINDOW", 0)
+	if janela <= 0 {
+		return
+	}
+	guardaNomes = &guardaSubmissoes{janela: janela, nomes: make(map[string]time.Time)}
+}
+
+// registrar marca nome como enviado agora e informa se ele pode prosseguir, isto
+// é, se não foi enviado dentro da janela.
+func (g *guardaSubmissoes) registrar(nome string) bool {
+	if g == nil {
+		return true
+	}
+	nome = chaveUnicidadeNome(nome)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	agora := time.Now()
+	if enviado, ok := g.nomes[nome]; ok && agora.Sub(enviado) < g.janela {
+		return false
+	}
+
+	// As entradas vivem poucos segundos; a varredura acontece só quando o mapa
+	// cresce, para não custar nada no caso comum.
+	if len(g.nomes) >= 1000 {
+		for n, enviado := range g.nomes {
+			if agora.Sub(enviado) >= g.janela {
+				delete(g.nomes, n)
+			}
+		}
+	}
+	g.nomes[nome] = agora
+	return true
+}
+
+// liberar esquece nome, usado quando o cadastro falhou e pode ser tentado de
+// novo imediatamente.
+func (g *guardaSubmissoes) liberar(nome string) {
+	if g == nil {
+		return
+	}
+	nome = chaveUnicidadeNome(nome)
+	g.mu.Lock()
+	delete(g.nomes, nome)
+	g.mu.Unlock()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// pedidoEncerramento é fechado quando o encerramento é pedido pela API.
+	pedidoEncerramento = make(chan struct{})
+	encerrarUmaVez     sync.Once
+)
+
+// solicitarEncerramento pede o encerramento gracioso do servidor. Chamadas
+// repetidas não têm efeito.
+func solicitarEncerramento() {
+	encerrarUmaVez.Do(func() { close(pedidoEncerramento) })
+}
+
+// encerrarServidor atende POST /admin/shutdown, registrado só com
+// ENABLE_ADMIN_SHUTDOWN=true. Permite que testes de ponta a ponta parem o
+// servidor sem enviar sinais do sistema operacional. A resposta é entregue
+// antes do encerramento, que espera as requisições em andamento.
+func encerrarServidor(w http.ResponseWriter, r *http.Request) {
+	responderJSON(w, r, http.StatusAccepted, map[string]string{"status": "encerrando"})
+	solicitarEncerramento()
+}
+
+// encerrarAoSinal encerra servidor graciosamente ao receber SIGINT ou SIGTERM,
+// ou quando solicitarEncerramento é chamada. As requisições em andamento têm
+// até SHUTDOWN_TIMEOUT (10s) para terminar; as conexões SSE, que não terminam
+// sozinhas, são encerradas logo no início. O canal devolvido é fechado quando o
+// encerramento termina.
+func encerrarAoSinal(servidor *http.Server) <-chan struct{} {
+	prazo := lerEnvDuracao("SHUTDOWN_TIMEOUT", 10*time.Second)
+	encerrado := make(chan struct{})
+	servidor.RegisterOnShutdown(encerrarEventos)
+
+	go func() {
+		defer close(encerrado)
+
+		sinais := make(chan os.Signal, 1)
+		signal.Notify(sinais, os.Interrupt, syscall.SIGTERM)
+		select {
+		case s := <-sinais:
+			log.Printf("Sinal %v recebido, encerrando o servidor", s)
+		case <-pedidoEncerramento:
+			log.Print("Encerramento solicitado pela API")
+		}
+		signal.Stop(sinais)
+
+		ctx, cancel := context.WithTimeout(context.Background(), prazo)
+		defer cancel()
+		if err := servidor.Shutdown(ctx); err != nil {
+			log.Printf("Erro ao encerrar o servidor: %v", err)
+		}
+	}()
+	return encerrado
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// etagListas, configurável por LIST_ETAG, liga o ETag de GET /pessoas.
+var etagListas = lerEnvBool("LIST_ETAG", true)
+
+// EstadoColecao resume a tabela de pessoas. Versao cresce a cada transação de
+// escrita, na ordem dos commits (veja confirmarEscrita).
+type EstadoColecao struct {
+	Versao int64
+}
+
+// etagColecao monta o ETag fraco de uma listagem a partir do estado da tabela e
+// de tudo na requisição que muda o corpo: a query (filtros, paginação, formato)
+// e o Accept (JSON ou JSON:API).
+func etagColecao(r *http.Request, estado EstadoColecao) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%s|%s|%t", estado.Versao, r.URL.RawQuery, r.Header.Get("Accept"), chavesCamelCase)
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagCorresponde aplica a comparação fraca de If-None-Match: o cabeçalho pode
+// trazer "*" ou uma lista de ETags, com ou sem o prefixo W/.
+func etagCorresponde(cabecalho, etag string) bool {
+	if cabecalho == "" {
+		return false
+	}
+	for _, candidato := range strings.Split(cabecalho, ",") {
+		candidato = strings.TrimSpace(candidato)
+		if candidato == "*" || strings.TrimPrefix(candidato, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tipos de EventoPessoa.
+const (
+	eventoCriado     = "created"
+	eventoAtualizado = "updated"
+	eventoRemovido   = "deleted"
+)
+
+// EventoPessoa descreve uma alteração bem-sucedida em uma pessoa.
+type EventoPessoa struct {
+	Type      string    `json:"type"`
+	Pessoa    Pessoa    `json:"pessoa"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// publicarEvento avisa os interessados sobre uma alteração. Deve ser chamada
+// pelos handlers somente depois que a mutação foi gravada, e nunca bloqueia a
+// requisição.
+func publicarEvento(tipo string, p Pessoa) {
+	evento := EventoPessoa{Type: tipo, Pessoa: p, Timestamp: time.Now().UTC()}
+	enfileirarWebhook(evento)
+	assinantesEventos.publicar(evento)
+}
+
+// assinantesEventos distribui os eventos publicados para cada conexão SSE.
+var assinantesEventos = &hubEventos{assinantes: make(map[chan EventoPessoa]struct{})}
+
+// hubEventos é um fan-out em memória: cada assinante tem seu próprio canal.
+type hubEventos struct {
+	mu         sync.Mutex
+	assinantes map[chan EventoPessoa]struct{}
+}
+
+// assinar registra um novo assinante e devolve seu canal.
+func (h *hubEventos) assinar() chan EventoPessoa {
+	canal := make(chan EventoPessoa, 16)
+	h.mu.Lock()
+	h.assinantes[canal] = struct{}{}
+	h.mu.Unlock()
+	return canal
+}
+
+// cancelar remove o assinante. O canal não é fechado para que publicar nunca
+// escreva num canal fechado.
+func (h *hubEventos) cancelar(canal chan EventoPessoa) {
+	h.mu.Lock()
+	delete(h.assinantes, canal)
+	h.mu.Unlock()
+}
+
+// publicar entrega o evento a todos os assinantes. Um assinante lento que está
+// com o canal cheio perde o evento em vez de atrasar os demais.
+func (h *hubEventos) publicar(evento EventoPessoa) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for canal := range h.assinantes {
+		select {
+		case canal <- evento:
+		default:
+		}
+	}
+}
+
+var (
+	// fimEventos é fechado no encerramento do servidor, terminando as conexões
+	// SSE: Server.Shutdown não cancela requisições em andamento, e cada stream
+	// aberto seguraria o encerramento até SHUTDOWN_TIMEOUT.
+	fimEventos         = make(chan struct{})
+	encerrarEventosUma sync.Once
+)
+
+// encerrarEventos encerra todas as conexões SSE. Chamadas repetidas não têm
+// efeito.
+func encerrarEventos() {
+	encerrarEventosUma.Do(func() { close(fimEventos) })
+}
+
+// intervaloHeartbeat é o intervalo dos comentários que mantêm a conexão SSE
+// aberta através de proxies.
+const intervaloHeartbeat = 15 * time.Second
+
+// transmitirEventos mantém uma conexão Server-Sent Events e envia cada
+// alteração em pessoas assim que ela acontece.
+func transmitirEventos(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		responderErro(w, r, http.StatusInternalServerError, mensagem(r, "streaming_indisponivel"))
+		return
+	}
+
+	canal := assinantesEventos.assinar()
+	defer assinantesEventos.cancelar(canal)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(intervaloHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-fimEventos:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case evento := <-canal:
+			dados, err := codificarJSON(evento)
+			if err != nil {
+				log.Println("Erro ao codificar evento:", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evento.Type, dados)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// expansoes são as relações aceitas em ?expand=, cada uma com a função que
+// carrega os registros relacionados e os embute na pessoa. Está vazia até que
+// existam tabelas relacionadas (como enderecos); cada nova relação entra aqui
+// com seu campo em Pessoa.
+var expansoes = map[string]func(ctx context.Context, p *Pessoa) error{}
+
+// lerExpansoes lê ?expand=, uma lista separada por vírgulas, recusando as
+// relações que não estão em expansoes.
+func lerExpansoes(r *http.Request) ([]string, error) {
+	var pedidas []string
+	vistas := make(map[string]bool)
+	for _, item := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || vistas[item] {
+			continue
+		}
+		if _, ok := expansoes[item]; !ok {
+			return nil, novoErro("expand_invalido", item)
+		}
+		vistas[item] = true
+		pedidas = append(pedidas, item)
+	}
+	return pedidas, nil
+}
+
+// expandir embute em p as relações pedidas.
+func expandir(ctx context.Context, p *Pessoa, pedidas []string) error {
+	for _, relacao := range pedidas {
+		if err := expansoes[relacao](ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tamanhoLoteExportacao é quantas linhas as exportações buscam por consulta.
+const tamanhoLoteExportacao = 10000
+
+// percorrerPessoas lê todas as pessoas em lotes por id (WHERE id > último) e
+// chama processar com cada lote, parando no primeiro erro. Assim a memória fica
+// constante e nenhuma consulta segura o banco por muito tempo.
+func percorrerPessoas(ctx context.Context, processar func(lote []Pessoa) error) error {
+	proximo := 0
+	for {
+		lote, err := repositorio.List(ctx, FiltroPessoas{MinID: &proximo, Limite: tamanhoLoteExportacao})
+		if err != nil {
+			return err
+		}
+		if err := processar(lote); err != nil {
+			return err
+		}
+		if len(lote) < tamanhoLoteExportacao {
+			return nil
+		}
+		proximo = lote[len(lote)-1].ID + 1
+	}
+}
+
+// exportarPessoasCSV responde com todas as pessoas em CSV (id, nome,
+// criado_em), escrevendo e enviando ao cliente cada lote de percorrerPessoas
+// antes de buscar o próximo.
+func exportarPessoasCSV(w http.ResponseWriter, r *http.Request) {
+	flusher, _ := w.(http.Flusher)
+	var escritor *csv.Writer
+
+	err := percorrerPessoas(r.Context(), func(lote []Pessoa) error {
+		if escritor == nil {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="pessoas.csv"`)
+			escritor = csv.NewWriter(w)
+			escritor.Write([]string{"id", "nome", "criado_em"})
+		}
+		for _, p := range lote {
+			criadoEm := ""
+			if p.CriadoEm != nil {
+				criadoEm = p.CriadoEm.Format(time.RFC3339)
+			}
+			escritor.Write([]string{strconv.Itoa(p.ID), p.Nome, criadoEm})
+		}
+
+		escritor.Flush()
+		if err := escritor.Error(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		finalizarExportacaoComErro(w, r, escritor != nil, err)
+	}
+}
+
+// aceitaGzip informa se o cliente aceita respostas gzip em Accept-Encoding.
+func aceitaGzip(r *http.Request) bool {
+	for _, item := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		codificacao, parametros, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if strings.EqualFold(strings.TrimSpace(codificacao), "gzip") {
+			return strings.ReplaceAll(parametros, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// transmitirPessoasNDJSON responde com todas as pessoas em NDJSON, uma por
+// linha, enviando cada lote de percorrerPessoas assim que ele é lido. Com
+// Accept-Encoding: gzip a resposta é comprimida; o gzip é esvaziado a cada lote
+// para que o cliente continue recebendo os dados aos poucos.
+func transmitirPessoasNDJSON(w http.ResponseWriter, r *http.Request) {
+	flusher, _ := w.(http.Flusher)
+	var saida io.Writer
+	var compressor *gzip.Writer
+
+	err := percorrerPessoas(r.Context(), func(lote []Pessoa) error {
+		if saida == nil {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Add("Vary", "Accept-Encoding")
+			saida = w
+			if aceitaGzip(r) {
+				w.Header().Set("Content-Encoding", "gzip")
+				compressor = gzip.NewWriter(w)
+				saida = compressor
+			}
+		}
+		for i := range lote {
+			preencherDerivados(r, &lote[i])
+			linha, err := codificarJSON(lote[i])
+			if err != nil {
+				return err
+			}
+			if _, err := saida.Write(append(linha, '\n')); err != nil {
+				return err
+			}
+		}
+
+		if compressor != nil {
+			if err := compressor.Flush(); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if compressor != nil {
+		if errFechar := compressor.Close(); err == nil {
+			err = errFechar
+		}
+	}
+	if err != nil {
+		finalizarExportacaoComErro(w, r, saida != nil, err)
+	}
+}
+
+// finalizarExportacaoComErro trata um erro de exportação. Antes de qualquer
+// dado enviado ainda é possível responder com o erro; depois, o status já foi
+// enviado, então a resposta é só interrompida e o erro fica no log.
+func finalizarExportacaoComErro(w http.ResponseWriter, r *http.Request, iniciada bool, err error) {
+	if !iniciada {
+		responderErroBanco(w, r, "erro_buscar_pessoas", err)
+		return
+	}
+	log.Printf("[%s] Exportação interrompida (%s): %v", requestID(r.Context()), caminhoOriginal(r), err)
+}
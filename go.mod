@@ -3,9 +3,12 @@ module router_modulo
 go 1.22.1
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/go-sql-driver/mysql v1.8.1
+	github.com/google/uuid v1.6.0
 	github.com/gorilla/mux v1.8.1
+	golang.org/x/text v0.21.0
 )
 
 // Dependências indiretas
-require filippo.io/edwards25519 v1.1.0 // indirect
\ No newline at end of file
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// EstadoSaude é a resposta dos endpoints de saúde.
+type EstadoSaude struct {
+	Status       string            `json:"status"`
+	Verificacoes map[string]string `json:"verificacoes,omitempty"`
+}
+
+// verificarProntidao confirma que o banco responde e que a migração foi
+// aplicada, ou seja, que as tabelas e as colunas de colunasEsperadas existem.
+func verificarProntidao(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := dbConn.PingContext(ctx); err != nil {
+		return err
+	}
+	if dbLeitura != nil && dbLeitura != dbConn {
+		if err := dbLeitura.PingContext(ctx); err != nil {
+			return fmt.Errorf("réplica de leitura: %w", err)
+		}
+	}
+
+	for tabela, esperadas := range colunasEsperadas() {
+		args := []any{tabela}
+		for _, coluna := range esperadas {
+			args = append(args, coluna)
+		}
+		var colunas int
+		err := dbConn.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+			AND COLUMN_NAME IN (`+strings.TrimSuffix(strings.Repeat("?,", len(esperadas)), ",")+`)`, args...).Scan(&colunas)
+		if err != nil {
+			return err
+		}
+		if colunas != len(esperadas) {
+			return fmt.Errorf("migração pendente na tabela %s", tabela)
+		}
+	}
+	return nil
+}
+
+// vivacidade responde 200 enquanto o processo estiver de pé. Não consulta o
+// banco de propósito: uma queda passageira do MySQL não deve fazer o
+// orquestrador reiniciar o pod.
+func vivacidade(w http.ResponseWriter, r *http.Request) {
+	responderJSON(w, r, http.StatusOK, EstadoSaude{Status: "ok"})
+}
+
+// descreverFalhaBanco registra a falha da verificação no log e devolve o texto
+// exibido na resposta: o erro em si apenas com exporDetalhesErro.
+func descreverFalhaBanco(r *http.Request, err error) string {
+	log.Printf("[%s] Verificação do banco falhou: %v", requestID(r.Context()), err)
+	if exporDetalhesErro {
+		return err.Error()
+	}
+	return "indisponivel"
+}
+
+// resultadoMonitor é a última verificação feita por iniciarMonitorBanco.
+type resultadoMonitor struct {
+	err error
+}
+
+// ultimoMonitor fica nil enquanto o monitor está desligado.
+var ultimoMonitor atomic.Pointer[resultadoMonitor]
+
+// iniciarMonitorBanco, com DB_HEALTHCHECK_INTERVAL definido, roda
+// verificarProntidao nesse intervalo em segundo plano, registrando no log cada
+// mudança de estado; /readyz e /health passam a responder com o último
+// resultado, de modo que um pool quebrado é notado mesmo sem sondas. O monitor
+// para quando ctx é cancelado.
+func iniciarMonitorBanco(ctx context.Context) {
+	intervalo := lerEnvDuracao("DB_HEALTHCHECK_INTERVAL", 0)
+	if intervalo <= 0 {
+		return
+	}
+
+	verificar := func() {
+		err := verificarProntidao(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		anterior := ultimoMonitor.Swap(&resultadoMonitor{err: err})
+		switch {
+		case err != nil && (anterior == nil || anterior.err == nil):
+			log.Printf("Banco indisponível: %v", err)
+		case err == nil && anterior != nil && anterior.err != nil:
+			log.Print("Banco disponível novamente")
+		}
+	}
+	verificar()
+
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				verificar()
+			}
+		}
+	}()
+}
+
+// estadoProntidao devolve o último resultado do monitor ou, com ele desligado,
+// verifica o banco na hora.
+func estadoProntidao(ctx context.Context) error {
+	if ultimo := ultimoMonitor.Load(); ultimo != nil {
+		return ultimo.err
+	}
+	return verificarProntidao(ctx)
+}
+
+// prontidao responde 200 apenas quando o serviço pode atender requisições,
+// isto é, quando o banco está acessível e migrado.
+func prontidao(w http.ResponseWriter, r *http.Request) {
+	if err := estadoProntidao(r.Context()); err != nil {
+		responderJSON(w, r, http.StatusServiceUnavailable, EstadoSaude{Status: "indisponivel", Verificacoes: map[string]string{"banco": descreverFalhaBanco(r, err)}})
+		return
+	}
+	responderJSON(w, r, http.StatusOK, EstadoSaude{Status: "ok"})
+}
+
+// saude agrega as verificações de vivacidade e prontidão.
+func saude(w http.ResponseWriter, r *http.Request) {
+	estado := EstadoSaude{
+		Status:       "ok",
+		Verificacoes: map[string]string{"processo": "ok", "banco": "ok"},
+	}
+
+	status := http.StatusOK
+	if err := estadoProntidao(r.Context()); err != nil {
+		estado.Status = "degradado"
+		estado.Verificacoes["banco"] = descreverFalhaBanco(r, err)
+		status = http.StatusServiceUnavailable
+	}
+	responderJSON(w, r, status, estado)
+}
+
+// DiferencaTabela compara uma tabela do banco com o que a aplicação espera.
+// ColunasExtras não é um problema, só informa colunas que o código não usa.
+type DiferencaTabela struct {
+	Tabela          string   `json:"tabela"`
+	Existe          bool     `json:"existe"`
+	ColunasFaltando []string `json:"colunas_faltando"`
+	ColunasExtras   []string `json:"colunas_extras"`
+	IndicesFaltando []string `json:"indices_faltando"`
+}
+
+// RelatorioEsquema é a resposta de /admin/schema-check.
+type RelatorioEsquema struct {
+	OK      bool              `json:"ok"`
+	Tabelas []DiferencaTabela `json:"tabelas"`
+}
+
+// compararEsquema confere em information_schema as tabelas, colunas e índices
+// de colunasEsperadas e indicesEsperados.
+func compararEsquema(ctx context.Context) (RelatorioEsquema, error) {
+	relatorio := RelatorioEsquema{OK: true, Tabelas: []DiferencaTabela{}}
+	colunas, indices := colunasEsperadas(), indicesEsperados()
+
+	tabelas := make([]string, 0, len(colunas))
+	for tabela := range colunas {
+		tabelas = append(tabelas, tabela)
+	}
+	sort.Strings(tabelas)
+
+	for _, tabela := range tabelas {
+		diferenca := DiferencaTabela{Tabela: tabela, ColunasFaltando: []string{}, ColunasExtras: []string{}, IndicesFaltando: []string{}}
+
+		existentes, err := lerNomesEsquema(ctx, consultaColunas, tabela)
+		if err != nil {
+			return relatorio, err
+		}
+		diferenca.Existe = len(existentes) > 0
+
+		esperadas := make(map[string]bool)
+		for _, coluna := range colunas[tabela] {
+			esperadas[coluna] = true
+			if !existentes[coluna] {
+				diferenca.ColunasFaltando = append(diferenca.ColunasFaltando, coluna)
+			}
+		}
+		for coluna := range existentes {
+			if !esperadas[coluna] {
+				diferenca.ColunasExtras = append(diferenca.ColunasExtras, coluna)
+			}
+		}
+		sort.Strings(diferenca.ColunasExtras)
+
+		existentes, err = lerNomesEsquema(ctx, consultaIndices, tabela)
+		if err != nil {
+			return relatorio, err
+		}
+		for _, indice := range indices[tabela] {
+			if !existentes[strings.ToLower(indice)] {
+				diferenca.IndicesFaltando = append(diferenca.IndicesFaltando, indice)
+			}
+		}
+
+		if !diferenca.Existe || len(diferenca.ColunasFaltando) > 0 || len(diferenca.IndicesFaltando) > 0 {
+			relatorio.OK = false
+		}
+		relatorio.Tabelas = append(relatorio.Tabelas, diferenca)
+	}
+	return relatorio, nil
+}
+
+// verificarEsquemaAdmin atende GET /admin/schema-check, registrado só com
+// ADMIN_TOKEN, para conferir um esquema mantido fora da aplicação logo após a
+// migração, antes que a diferença apareça como erros 500.
+func verificarEsquemaAdmin(w http.ResponseWriter, r *http.Request) {
+	relatorio, err := compararEsquema(r.Context())
+	if err != nil {
+		responderErroBanco(w, r, "erro_verificar_esquema", err)
+		return
+	}
+	responderJSON(w, r, http.StatusOK, relatorio)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// idiomasSuportados são os idiomas do catálogo; o primeiro é o padrão.
+var idiomasSuportados = []language.Tag{language.BrazilianPortuguese, language.English}
+
+var seletorIdioma = language.NewMatcher(idiomasSuportados)
+
+// mensagens é o catálogo de textos devolvidos aos clientes, por idioma e chave.
+// Os textos podem ter verbos do fmt, preenchidos pelos argumentos de mensagem.
+var mensagens = map[language.Tag]map[string]string{
+	language.BrazilianPortuguese: {
+		"arquivo_ausente":           "campo \"arquivo\" ausente: %v",
+		"boas_vindas":               "Bem-vindo ao nosso serviço!",
+		"coluna_nome_ausente":       "coluna nome ausente",
+		"content_encoding_invalido": "Content-Encoding deve ser gzip ou identity",
+		"content_type_criacao":      "O Content-Type deve ser application/json, application/x-www-form-urlencoded ou multipart/form-data",
+		"content_type_csv":          "O Content-Type deve ser text/csv ou multipart/form-data",
+		"content_type_invalido":     "Content-Type inválido",
+		"content_type_json":         "O Content-Type deve ser application/json",
+		"corpo_excedido":            "o corpo da requisição excede o limite de %d bytes",
+		"corpo_gzip_invalido":       "Corpo gzip inválido: %v",
+		"csv_invalido":              "Erro ao ler CSV: %v",
+		"duration_invalida":         "duration deve ser uma duração positiva, como 30m",
+		"erro_atualizar_pessoa":     "Erro ao atualizar pessoa",
+		"erro_atualizar_pessoas":    "Erro ao atualizar pessoas",
+		"erro_buscar_historico":     "Erro ao buscar histórico",
+		"erro_buscar_pessoa":        "Erro ao buscar pessoa",
+		"erro_buscar_pessoas":       "Erro ao buscar pessoas",
+		"erro_buscar_sugestoes":     "Erro ao buscar sugestões",
+		"erro_contar_pessoas":       "Erro ao contar pessoas",
+		"erro_deletar_pessoa":       "Erro ao deletar pessoa",
+		"erro_executar_lote":        "Erro ao executar o lote",
+		"erro_inserir_pessoa":       "Erro ao inserir pessoa",
+		"erro_inserir_pessoas":      "Erro ao inserir pessoas",
+		"erro_verificar_esquema":    "Erro ao verificar o esquema",
+		"expand_invalido":           "expand não suportado: %q",
+		"id_divergente":             "id do corpo não corresponde ao id da URL",
+		"id_invalido":               "ID inválido",
+		"id_repetido":               "id repetido no lote",
+		"ids_demais":                "ids aceita no máximo %d ids",
+		"if_none_match_invalido":    "If-None-Match só aceita * na criação",
+		"inicializando":             "Serviço em inicialização; tente novamente em instantes",
+		"json_invalido":             "Erro ao decodificar JSON: %v",
+		"limit_faixa":               "limit deve estar entre %d e %d",
+		"limite_taxa":               "Limite de requisições excedido; tente novamente mais tarde",
+		"lote_desfeito":             "Nenhuma alteração foi gravada: há itens inválidos ou inexistentes",
+		"lote_excedido":             "o lote aceita no máximo %d itens",
+		"lotes_simultaneos_demais":  "Operações em lote simultâneas demais; tente novamente em instantes",
+		"min_max_id":                "min_id deve ser menor ou igual a max_id",
+		"nao_autorizado":            "Token de administração ausente ou inválido",
+		"nome_bloqueado":            "o nome contém um termo não permitido: %q",
+		"nome_caractere_controle":   "o nome não pode conter caracteres de controle",
+		"nome_longo":                "o nome deve ter no máximo %d caracteres",
+		"nome_obrigatorio":          "o nome é obrigatório",
+		"nome_utf8_invalido":        "o nome não é UTF-8 válido",
+		"nulls_invalido":            "nulls deve ser first ou last",
+		"operacao_invalida":         "método de operação inválido: %q (use create, update ou delete)",
+		"operacao_sem_corpo":        "a operação exige body",
+		"order_invalido":            "order deve ser asc ou desc",
+		"paginacao_conflito":        "offset/limit e page/per_page descrevem páginas diferentes",
+		"paginacao_invalida":        "offset deve ser >= 0 e limit, page e per_page devem ser >= 1",
+		"paginacao_maxima":          "limit e per_page devem ser no máximo %d",
+		"param_data":                "%s deve ser uma data RFC 3339 ou AAAA-MM-DD",
+		"param_inteiro":             "%s deve ser um número inteiro",
+		"pessoa_duplicada":          "Já existe uma pessoa com esse nome",
+		"pessoa_nao_encontrada":     "Pessoa não encontrada",
+		"prefixo_curto":             "prefix deve ter ao menos %d caracteres",
+		"preserve_order_invalido":   "preserve_order exige ids e não pode ser combinado com sort",
+		"query_longa":               "Query string muito longa",
+		"read_only_ausente":         "read_only é obrigatório",
+		"redirect_invalido":         "redirect_to deve ser um caminho local",
+		"servico_sobrecarregado":    "Serviço sobrecarregado, tente novamente em instantes",
+		"simultaneas_demais":        "Requisições simultâneas demais deste cliente; tente novamente em instantes",
+		"somente_leitura":           "Serviço em modo somente leitura para manutenção; tente novamente mais tarde",
+		"sort_invalido":             "sort deve ser id, nome, criado_em ou atualizado_em",
+		"streaming_indisponivel":    "Streaming não suportado",
+		"submissao_duplicada":       "Cadastro idêntico enviado há instantes; ignorado",
+		"time_format_invalido":      "time_format deve ser unix ou rfc3339",
+		"timeout_invalido":          "X-Request-Timeout deve ser um número positivo de milissegundos",
+		"uuid_invalido":             "uuid inválido",
+	},
+	language.English: {
+		"arquivo_ausente":           "missing \"arquivo\" field: %v",
+		"boas_vindas":               "Welcome to our service!",
+		"coluna_nome_ausente":       "missing nome column",
+		"content_encoding_invalido": "Content-Encoding must be gzip or identity",
+		"content_type_criacao":      "Content-Type must be application/json, application/x-www-form-urlencoded or multipart/form-data",
+		"content_type_csv":          "Content-Type must be text/csv or multipart/form-data",
+		"content_type_invalido":     "invalid Content-Type",
+		"content_type_json":         "Content-Type must be application/json",
+		"corpo_excedido":            "the request body exceeds the limit of %d bytes",
+		"corpo_gzip_invalido":       "invalid gzip body: %v",
+		"csv_invalido":              "error reading CSV: %v",
+		"duration_invalida":         "duration must be a positive duration such as 30m",
+		"erro_atualizar_pessoa":     "Error updating person",
+		"erro_atualizar_pessoas":    "Error updating people",
+		"erro_buscar_historico":     "Error fetching history",
+		"erro_buscar_pessoa":        "Error fetching person",
+		"erro_buscar_pessoas":       "Error fetching people",
+		"erro_buscar_sugestoes":     "Error fetching suggestions",
+		"erro_contar_pessoas":       "Error counting people",
+		"erro_deletar_pessoa":       "Error deleting person",
+		"erro_executar_lote":        "Error executing batch",
+		"erro_inserir_pessoa":       "Error inserting person",
+		"erro_inserir_pessoas":      "Error inserting people",
+		"erro_verificar_esquema":    "Error checking the schema",
+		"expand_invalido":           "unsupported expand: %q",
+		"id_divergente":             "body id does not match the URL id",
+		"id_invalido":               "Invalid ID",
+		"id_repetido":               "id repeated in batch",
+		"ids_demais":                "ids accepts at most %d ids",
+		"if_none_match_invalido":    "If-None-Match only accepts * on create",
+		"inicializando":             "Service is starting; try again shortly",
+		"json_invalido":             "Error decoding JSON: %v",
+		"limit_faixa":               "limit must be between %d and %d",
+		"limite_taxa":               "Rate limit exceeded; try again later",
+		"lote_desfeito":             "No changes were saved: some items are invalid or do not exist",
+		"lote_excedido":             "a batch accepts at most %d items",
+		"lotes_simultaneos_demais":  "Too many concurrent bulk operations; try again shortly",
+		"min_max_id":                "min_id must be less than or equal to max_id",
+		"nao_autorizado":            "Missing or invalid admin token",
+		"nome_bloqueado":            "name contains a disallowed term: %q",
+		"nome_caractere_controle":   "name must not contain control characters",
+		"nome_longo":                "name must be at most %d characters",
+		"nome_obrigatorio":          "name is required",
+		"nome_utf8_invalido":        "name is not valid UTF-8",
+		"nulls_invalido":            "nulls must be first or last",
+		"operacao_invalida":         "invalid operation method: %q (use create, update or delete)",
+		"operacao_sem_corpo":        "operation requires a body",
+		"order_invalido":            "order must be asc or desc",
+		"paginacao_conflito":        "offset/limit and page/per_page describe different pages",
+		"paginacao_invalida":        "offset must be >= 0 and limit, page and per_page must be >= 1",
+		"paginacao_maxima":          "limit and per_page must be at most %d",
+		"param_data":                "%s must be an RFC 3339 or YYYY-MM-DD date",
+		"param_inteiro":             "%s must be an integer",
+		"pessoa_duplicada":          "A person with this name already exists",
+		"pessoa_nao_encontrada":     "Person not found",
+		"prefixo_curto":             "prefix must have at least %d characters",
+		"preserve_order_invalido":   "preserve_order requires ids and cannot be combined with sort",
+		"query_longa":               "Query string too long",
+		"read_only_ausente":         "read_only is required",
+		"redirect_invalido":         "redirect_to must be a local path",
+		"servico_sobrecarregado":    "Service overloaded, please retry shortly",
+		"simultaneas_demais":        "Too many concurrent requests from this client; try again shortly",
+		"somente_leitura":           "Service is read-only for maintenance; try again later",
+		"sort_invalido":             "sort must be id, nome, criado_em or atualizado_em",
+		"streaming_indisponivel":    "Streaming not supported",
+		"submissao_duplicada":       "Identical create submitted moments ago; ignored",
+		"time_format_invalido":      "time_format must be unix or rfc3339",
+		"timeout_invalido":          "X-Request-Timeout must be a positive number of milliseconds",
+		"uuid_invalido":             "invalid uuid",
+	},
+}
+
+// idiomaRequisicao escolhe o idioma do catálogo pelo cabeçalho Accept-Language,
+// usando pt-BR quando nenhum idioma pedido é suportado.
+func idiomaRequisicao(r *http.Request) language.Tag {
+	pedidos, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	_, indice, confianca := seletorIdioma.Match(pedidos...)
+	if confianca == language.No {
+		return idiomasSuportados[0]
+	}
+	return idiomasSuportados[indice]
+}
+
+// mensagem devolve o texto da chave no idioma da requisição.
+func mensagem(r *http.Request, chave string, args ...any) string {
+	return traduzir(idiomaRequisicao(r), chave, args...)
+}
+
+// traduzir formata a chave no idioma informado, caindo para pt-BR e, em último
+// caso, para a própria chave.
+func traduzir(idioma language.Tag, chave string, args ...any) string {
+	texto, ok := mensagens[idioma][chave]
+	if !ok {
+		if texto, ok = mensagens[idiomasSuportados[0]][chave]; !ok {
+			texto = chave
+		}
+	}
+	if len(args) == 0 {
+		return texto
+	}
+	return fmt.Sprintf(texto, args...)
+}
+
+// erroLocalizado é um erro destinado ao cliente cujo texto vem do catálogo.
+// Error() usa o idioma padrão; mensagemErro traduz para o idioma da requisição.
+type erroLocalizado struct {
+	chave string
+	args  []any
+}
+
+func (e *erroLocalizado) Error() string {
+	return traduzir(idiomasSuportados[0], e.chave, e.args...)
+}
+
+// novoErro cria um erroLocalizado.
+func novoErro(chave string, args ...any) error {
+	return &erroLocalizado{chave: chave, args: args}
+}
+
+// mensagemErro devolve o texto de err no idioma da requisição quando ele é um
+// erroLocalizado, ou err.Error() nos demais casos.
+func mensagemErro(r *http.Request, err error) string {
+	var localizado *erroLocalizado
+	if errors.As(err, &localizado) {
+		return mensagem(r, localizado.chave, localizado.args...)
+	}
+	return err.Error()
+}
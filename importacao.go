@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// FalhaImportacao descreve uma linha do CSV que não pôde ser importada.
+type FalhaImportacao struct {
+	Linha int    `json:"linha"`
+	Erro  string `json:"erro"`
+}
+
+// ResumoImportacao é a resposta de uma importação de CSV.
+type ResumoImportacao struct {
+	Inseridos int               `json:"inseridos"`
+	Ignorados int               `json:"ignorados"`
+	Falhas    []FalhaImportacao `json:"falhas"`
+}
+
+// abrirCSV devolve o conteúdo CSV da requisição, aceitando tanto um corpo
+// text/csv quanto um upload multipart no campo "arquivo".
+func abrirCSV(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, novoErro("content_type_invalido")
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return r.Body, nil
+	case "multipart/form-data":
+		arquivo, _, err := r.FormFile("arquivo")
+		var excedido *http.MaxBytesError
+		if errors.As(err, &excedido) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, novoErro("arquivo_ausente", err)
+		}
+		return arquivo, nil
+	default:
+		return nil, novoErro("content_type_csv")
+	}
+}
+
+// importarPessoas cadastra pessoas a partir de um CSV. A primeira coluna (ou a
+// coluna "nome", se houver cabeçalho) contém o nome. Linhas inválidas são
+// ignoradas e relatadas no resumo; as válidas são inseridas de uma vez, numa
+// única transação. Um CSV com mais de maxItensLote linhas é recusado com 400.
+func importarPessoas(w http.ResponseWriter, r *http.Request) {
+	arquivo, err := abrirCSV(r)
+	if corpoExcedido(w, r, err) {
+		return
+	}
+	if err != nil {
+		responderErro(w, r, http.StatusUnsupportedMediaType, mensagemErro(r, err))
+		return
+	}
+	defer arquivo.Close()
+
+	leitor := csv.NewReader(arquivo)
+	leitor.FieldsPerRecord = -1
+	leitor.TrimLeadingSpace = true
+
+	resumo := ResumoImportacao{Falhas: []FalhaImportacao{}}
+	var pessoas []Pessoa
+	colunaNome := 0
+	registros := 0
+
+	for leitura := 1; ; leitura++ {
+		registro, err := leitor.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var errCSV *csv.ParseError
+			if errors.As(err, &errCSV) {
+				resumo.Ignorados++
+				resumo.Falhas = append(resumo.Falhas, FalhaImportacao{Linha: errCSV.Line, Erro: errCSV.Err.Error()})
+				continue
+			}
+			if !corpoExcedido(w, r, err) {
+				responderErro(w, r, http.StatusBadRequest, mensagem(r, "csv_invalido", err))
+			}
+			return
+		}
+
+		// Linha física do início do registro, a mesma de csv.ParseError.Line,
+		// que difere da contagem de registros quando um campo entre aspas
+		// ocupa várias linhas.
+		linha, _ := leitor.FieldPos(0)
+
+		if leitura == 1 {
+			if indice, ok := indiceCabecalho(registro, "nome"); ok {
+				colunaNome = indice
+				continue
+			}
+		}
+
+		registros++
+		if registros > maxItensLote {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "lote_excedido", maxItensLote))
+			return
+		}
+
+		if colunaNome >= len(registro) {
+			resumo.Ignorados++
+			resumo.Falhas = append(resumo.Falhas, FalhaImportacao{Linha: linha, Erro: mensagem(r, "coluna_nome_ausente")})
+			continue
+		}
+
+		p := Pessoa{Nome: normalizarNome(registro[colunaNome])}
+		if err := validarPessoa(p); err != nil {
+			resumo.Ignorados++
+			resumo.Falhas = append(resumo.Falhas, FalhaImportacao{Linha: linha, Erro: mensagemErro(r, err)})
+			continue
+		}
+		pessoas = append(pessoas, p)
+	}
+
+	inseridas, err := repositorio.CreateMany(r.Context(), pessoas)
+	if err != nil {
+		responderErroBanco(w, r, "erro_inserir_pessoas", err)
+		return
+	}
+	for _, p := range inseridas {
+		publicarEvento(eventoCriado, p)
+	}
+
+	resumo.Inseridos = len(inseridas)
+	responderJSON(w, r, http.StatusOK, resumo)
+}
+
+// indiceCabecalho procura a coluna nome no registro, ignorando maiúsculas.
+func indiceCabecalho(registro []string, nome string) (int, bool) {
+	for i, coluna := range registro {
+		if strings.EqualFold(strings.TrimSpace(coluna), nome) {
+			return i, true
+		}
+	}
+	return 0, false
+}
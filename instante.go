@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Instante é um time.Time cuja serialização JSON pode ser RFC 3339 (padrão) ou
+// segundos Unix, conforme pedido em ?time_format. A escolha fica no próprio
+// valor porque MarshalJSON não tem acesso à requisição.
+type Instante struct {
+	time.Time
+	unix bool
+}
+
+// MarshalJSON serializa o instante em RFC 3339 ou, se pedido, como número de
+// segundos desde a época Unix.
+func (i Instante) MarshalJSON() ([]byte, error) {
+	if i.unix {
+		return strconv.AppendInt(nil, i.Unix(), 10), nil
+	}
+	return i.Time.MarshalJSON()
+}
+
+// Scan implementa sql.Scanner para colunas DATETIME lidas com parseTime.
+func (i *Instante) Scan(valor any) error {
+	t, ok := valor.(time.Time)
+	if !ok {
+		return fmt.Errorf("instante: tipo %T não suportado", valor)
+	}
+	i.Time = t
+	return nil
+}
+
+// formatarInstante devolve uma cópia de i no formato pedido em ?time_format
+// (unix ou rfc3339). A cópia evita alterar valores compartilhados, como os do
+// cache de leitura.
+func formatarInstante(formato string, i *Instante) *Instante {
+	if i == nil || formato != "unix" {
+		return i
+	}
+	copia := *i
+	copia.unix = true
+	return &copia
+}
+
+// formatoInstanteMiddleware recusa com 400 um ?time_format que não seja unix
+// nem rfc3339. A validação vem antes do handler para que uma escrita não seja
+// gravada e só depois respondida com erro.
+func formatoInstanteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("time_format") {
+		case "", "unix", "rfc3339":
+		default:
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "time_format_invalido"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// mediaTypeJSONAPI é o tipo de mídia da especificação JSON:API. Clientes que o
+// enviam em Accept recebem as respostas nesse formato; os demais continuam
+// recebendo JSON simples.
+const mediaTypeJSONAPI = "application/vnd.api+json"
+
+// RecursoJSONAPI é um objeto de recurso da JSON:API.
+type RecursoJSONAPI struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// ErroJSONAPI é um objeto de erro da JSON:API.
+type ErroJSONAPI struct {
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// DocumentoJSONAPI é o documento de topo de uma resposta JSON:API.
+type DocumentoJSONAPI struct {
+	Data   any               `json:"data,omitempty"`
+	Errors []ErroJSONAPI     `json:"errors,omitempty"`
+	Links  map[string]string `json:"links,omitempty"`
+}
+
+// aceitaJSONAPI informa se o cliente pediu JSON:API no cabeçalho Accept.
+func aceitaJSONAPI(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), mediaTypeJSONAPI)
+}
+
+// recursoPessoa converte a pessoa em recurso "pessoas". Os atributos são os
+// mesmos campos do JSON simples, exceto o id, que sobe para o recurso.
+func recursoPessoa(p Pessoa) RecursoJSONAPI {
+	var atributos map[string]any
+	dados, _ := json.Marshal(p)
+	json.Unmarshal(dados, &atributos)
+	delete(atributos, "id")
+
+	return RecursoJSONAPI{Type: "pessoas", ID: strconv.Itoa(p.ID), Attributes: atributos}
+}
+
+// documentoJSONAPI converte os corpos conhecidos em documento JSON:API. O
+// segundo retorno é falso para tipos sem representação JSON:API, que são
+// enviados como JSON simples.
+func documentoJSONAPI(status int, v any) (DocumentoJSONAPI, bool) {
+	switch corpo := v.(type) {
+	case DocumentoJSONAPI:
+		return corpo, true
+	case Pessoa:
+		return DocumentoJSONAPI{Data: recursoPessoa(corpo)}, true
+	case []Pessoa:
+		return DocumentoJSONAPI{Data: recursosPessoas(corpo)}, true
+	case RespostaErro:
+		return DocumentoJSONAPI{Errors: []ErroJSONAPI{{ID: corpo.RequestID, Status: strconv.Itoa(status), Detail: corpo.Error}}}, true
+	default:
+		return DocumentoJSONAPI{}, false
+	}
+}
+
+// recursosPessoas converte a lista, devolvendo [] (e não null) quando vazia.
+func recursosPessoas(pessoas []Pessoa) []RecursoJSONAPI {
+	recursos := make([]RecursoJSONAPI, len(pessoas))
+	for i, p := range pessoas {
+		recursos[i] = recursoPessoa(p)
+	}
+	return recursos
+}
+
+// linksPaginacao monta os links self, first, prev e next de uma página da
+// listagem no estilo offset/limit, mantendo os demais parâmetros da query.
+func linksPaginacao(r *http.Request, deslocamento, limite, quantidade int) map[string]string {
+	link := func(offset int) string {
+		params := r.URL.Query()
+		params.Del("page")
+		params.Del("per_page")
+		params.Set("offset", strconv.Itoa(offset))
+		params.Set("limit", strconv.Itoa(limite))
+		return (&url.URL{Path: caminhoOriginal(r), RawQuery: params.Encode()}).String()
+	}
+
+	links := map[string]string{
+		"self":  link(deslocamento),
+		"first": link(0),
+	}
+	if deslocamento > 0 {
+		links["prev"] = link(max(deslocamento-limite, 0))
+	}
+	if quantidade == limite {
+		links["next"] = link(deslocamento + limite)
+	}
+	return links
+}
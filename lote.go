@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Situações de um item de operação em lote.
+const (
+	loteCriado        = "criado"
+	loteAtualizado    = "atualizado"
+	loteRemovido      = "removido"
+	loteNaoEncontrado = "nao_encontrado"
+	loteInvalido      = "invalido"
+	loteIgnorado      = "ignorado"
+)
+
+// ErrLoteIncompleto indica que um lote tudo-ou-nada foi desfeito porque algum
+// item não pôde ser aplicado.
+var ErrLoteIncompleto = errors.New("lote desfeito: há itens inválidos ou inexistentes")
+
+// ResultadoLote é o resultado de um item de uma operação em lote.
+type ResultadoLote struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Erro   string `json:"erro,omitempty"`
+}
+
+// RespostaLote é a resposta das operações em lote. Requested é o número de
+// itens enviados e Affected o de linhas de fato gravadas, que fica menor quando
+// há ids inexistentes (ou zero num lote desfeito). Affected é contado a partir
+// das linhas travadas na transação, e não de RowsAffected, que no MySQL não
+// conta uma linha atualizada com os mesmos valores.
+type RespostaLote struct {
+	Error      string          `json:"error,omitempty"`
+	Requested  int             `json:"requested"`
+	Affected   int             `json:"affected"`
+	Resultados []ResultadoLote `json:"resultados"`
+}
+
+// maxItensLote, configurável por MAX_BULK_SIZE, limita o número de itens de
+// PUT /pessoas/bulk, de operações de POST /pessoas/batch e de linhas de
+// POST /pessoas/import, evitando transações enormes que seguram travas por
+// muito tempo. Lotes maiores devem ser divididos pelo cliente.
+var maxItensLote = lerEnvInt("MAX_BULK_SIZE", 1000)
+
+// semaforoLote limita, por MAX_CONCURRENT_BULK (padrão 3), quantas operações
+// em lote rodam ao mesmo tempo. Cada uma abre uma transação grande, e muitas
+// juntas esgotariam o pool de conexões antes do tráfego comum. Fica nil com
+// MAX_CONCURRENT_BULK=0, que desliga o limite.
+var semaforoLote = novoSemaforoLote(lerEnvInt("MAX_CONCURRENT_BULK", 3))
+
+// esperaLote, configurável por BULK_QUEUE_TIMEOUT, é quanto uma operação em
+// lote espera por uma vaga antes de ser recusada com 503.
+var esperaLote = lerEnvDuracao("BULK_QUEUE_TIMEOUT", 5*time.Second)
+
+func novoSemaforoLote(maximo int) chan struct{} {
+	if maximo <= 0 {
+		return nil
+	}
+	return make(chan struct{}, maximo)
+}
+
+// adquirirLote reserva uma vaga em semaforoLote, devolvendo a função que a
+// libera, ou ErrBancoSobrecarregado se a espera passar de esperaLote.
+func adquirirLote(ctx context.Context) (func(), error) {
+	metricaFilaLote.Add(1)
+	defer metricaFilaLote.Add(-1)
+
+	timer := time.NewTimer(esperaLote)
+	defer timer.Stop()
+
+	select {
+	case semaforoLote <- struct{}{}:
+		metricaUsoLote.Add(1)
+		return func() {
+			metricaUsoLote.Add(-1)
+			<-semaforoLote
+		}, nil
+	case <-timer.C:
+		return nil, ErrBancoSobrecarregado
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limitarLote envolve um handler de operação em lote com semaforoLote. As
+// excedentes esperam na fila e, passado esperaLote, recebem 503 com
+// Retry-After, sem afetar o tráfego comum.
+func limitarLote(next http.HandlerFunc) http.HandlerFunc {
+	if semaforoLote == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		liberar, err := adquirirLote(r.Context())
+		if err != nil {
+			w.Header().Set("Retry-After", "5")
+			responderErro(w, r, http.StatusServiceUnavailable, mensagem(r, "lotes_simultaneos_demais"))
+			return
+		}
+		defer liberar()
+		next(w, r)
+	}
+}
+
+// atualizarPessoasEmLote atualiza várias pessoas numa única transação. Por
+// padrão o lote é tudo-ou-nada: se algum item for inválido ou não existir,
+// nada é gravado e a resposta 422 aponta os itens com problema. Com
+// ?parcial=true os itens válidos são gravados e os demais apenas relatados.
+func atualizarPessoasEmLote(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		responderErro(w, r, http.StatusUnsupportedMediaType, mensagem(r, "content_type_json"))
+		return
+	}
+
+	var pessoas []Pessoa
+	if err := json.NewDecoder(r.Body).Decode(&pessoas); err != nil {
+		if !corpoExcedido(w, r, err) {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "json_invalido", err))
+		}
+		return
+	}
+	if len(pessoas) > maxItensLote {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "lote_excedido", maxItensLote))
+		return
+	}
+	parcial := r.URL.Query().Get("parcial") == "true"
+
+	resultados := make([]ResultadoLote, len(pessoas))
+	validas := make([]Pessoa, 0, len(pessoas))
+	indices := make(map[int]int, len(pessoas))
+	invalido := false
+
+	for i, p := range pessoas {
+		resultados[i].ID = p.ID
+		p.Nome = normalizarNome(p.Nome)
+
+		var err error
+		if p.ID <= 0 {
+			err = novoErro("id_invalido")
+		} else if _, repetido := indices[p.ID]; repetido {
+			err = novoErro("id_repetido")
+		} else {
+			err = validarPessoa(p)
+		}
+		if err != nil {
+			resultados[i].Status = loteInvalido
+			resultados[i].Erro = mensagemErro(r, err)
+			invalido = true
+			continue
+		}
+
+		indices[p.ID] = i
+		validas = append(validas, p)
+	}
+
+	if invalido && !parcial {
+		marcarIgnorados(resultados)
+		responderJSON(w, r, http.StatusUnprocessableEntity, RespostaLote{Error: mensagem(r, "lote_desfeito"), Requested: len(pessoas), Resultados: resultados})
+		return
+	}
+
+	atualizadas, err := repositorio.UpdateMany(r.Context(), validas, parcial)
+	if err != nil && !errors.Is(err, ErrLoteIncompleto) {
+		responderErroBanco(w, r, "erro_atualizar_pessoas", err)
+		return
+	}
+
+	for _, p := range validas {
+		i := indices[p.ID]
+		if atualizada, ok := atualizadas[p.ID]; ok {
+			resultados[i].Status = loteAtualizado
+			if err == nil {
+				cachePessoas.guardar(atualizada)
+				publicarEvento(eventoAtualizado, atualizada)
+			}
+		} else {
+			resultados[i].Status = loteNaoEncontrado
+		}
+	}
+
+	if err != nil {
+		marcarIgnorados(resultados)
+		responderJSON(w, r, http.StatusUnprocessableEntity, RespostaLote{Error: mensagem(r, "lote_desfeito"), Requested: len(pessoas), Resultados: resultados})
+		return
+	}
+	responderJSON(w, r, http.StatusOK, RespostaLote{Requested: len(pessoas), Affected: len(atualizadas), Resultados: resultados})
+}
+
+// marcarIgnorados marca como ignorados os itens válidos de um lote desfeito.
+func marcarIgnorados(resultados []ResultadoLote) {
+	for i := range resultados {
+		if resultados[i].Status == "" || resultados[i].Status == loteAtualizado {
+			resultados[i].Status = loteIgnorado
+		}
+	}
+}
+
+// Métodos aceitos nas operações de POST /pessoas/batch.
+const (
+	metodoCriacao     = "create"
+	metodoAtualizacao = "update"
+	metodoRemocao     = "delete"
+)
+
+// OperacaoLote é uma operação do envelope de POST /pessoas/batch: create leva
+// body; update leva id e body; delete leva id.
+type OperacaoLote struct {
+	Metodo string  `json:"method"`
+	ID     int     `json:"id,omitempty"`
+	Corpo  *Pessoa `json:"body,omitempty"`
+}
+
+// EnvelopeLote é o corpo de POST /pessoas/batch.
+type EnvelopeLote struct {
+	Operations []OperacaoLote `json:"operations"`
+}
+
+// ResultadoOperacao é o resultado de uma operação do envelope.
+type ResultadoOperacao struct {
+	Status string  `json:"status"`
+	Pessoa *Pessoa `json:"pessoa,omitempty"`
+	Erro   string  `json:"erro,omitempty"`
+}
+
+// RespostaEnvelope é a resposta de POST /pessoas/batch, com um resultado por
+// operação, na ordem do envelope. Requested e Affected seguem RespostaLote; uma
+// remoção de id inexistente não conta como afetada.
+type RespostaEnvelope struct {
+	Error      string              `json:"error,omitempty"`
+	Requested  int                 `json:"requested"`
+	Affected   int                 `json:"affected"`
+	Resultados []ResultadoOperacao `json:"resultados"`
+}
+
+// validarOperacao confere os campos exigidos pelo método da operação e
+// normaliza o corpo.
+func validarOperacao(op *OperacaoLote) error {
+	switch op.Metodo {
+	case metodoCriacao, metodoAtualizacao:
+		if op.Corpo == nil {
+			return novoErro("operacao_sem_corpo")
+		}
+		if op.Metodo == metodoAtualizacao && op.ID <= 0 {
+			return novoErro("id_invalido")
+		}
+		op.Corpo.Nome = normalizarNome(op.Corpo.Nome)
+		return validarPessoa(*op.Corpo)
+	case metodoRemocao:
+		if op.ID <= 0 {
+			return novoErro("id_invalido")
+		}
+		return nil
+	default:
+		return novoErro("operacao_invalida", op.Metodo)
+	}
+}
+
+// executarEnvelope executa as operações do envelope em ordem, numa única
+// transação. O envelope é tudo-ou-nada: se qualquer operação for inválida ou
+// falhar (por exemplo, atualizar um id inexistente), nada é gravado e a
+// resposta 422 traz o erro na operação culpada e "ignorado" nas demais.
+// Remover um id inexistente não é falha, como em DELETE /pessoas/{id}.
+func executarEnvelope(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		responderErro(w, r, http.StatusUnsupportedMediaType, mensagem(r, "content_type_json"))
+		return
+	}
+
+	var envelope EnvelopeLote
+	if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		if !corpoExcedido(w, r, err) {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "json_invalido", err))
+		}
+		return
+	}
+	operacoes := envelope.Operations
+	if len(operacoes) > maxItensLote {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "lote_excedido", maxItensLote))
+		return
+	}
+
+	resultados := make([]ResultadoOperacao, len(operacoes))
+	for i := range operacoes {
+		if err := validarOperacao(&operacoes[i]); err != nil {
+			falharEnvelope(w, r, resultados, i, loteInvalido, err)
+			return
+		}
+	}
+
+	pessoas, indice, err := repositorio.Batch(r.Context(), operacoes)
+	if err != nil {
+		if indice >= 0 && errors.Is(err, ErrPessoaNaoEncontrada) {
+			falharEnvelope(w, r, resultados, indice, loteNaoEncontrado, novoErro("pessoa_nao_encontrada"))
+			return
+		}
+		responderErroBanco(w, r, "erro_executar_lote", err)
+		return
+	}
+
+	afetadas := 0
+	for i, op := range operacoes {
+		p := pessoas[i]
+		if p.ID != 0 {
+			afetadas++
+		}
+		switch op.Metodo {
+		case metodoCriacao:
+			resultados[i].Status = loteCriado
+			publicarEvento(eventoCriado, p)
+		case metodoAtualizacao:
+			resultados[i].Status = loteAtualizado
+			cachePessoas.guardar(p)
+			publicarEvento(eventoAtualizado, p)
+		case metodoRemocao:
+			resultados[i].Status = loteRemovido
+			cachePessoas.remover(op.ID)
+			if p.ID != 0 {
+				publicarEvento(eventoRemovido, p)
+			}
+			continue
+		}
+		preencherDerivados(r, &p)
+		resultados[i].Pessoa = &p
+	}
+	responderJSON(w, r, http.StatusOK, RespostaEnvelope{Requested: len(operacoes), Affected: afetadas, Resultados: resultados})
+}
+
+// falharEnvelope responde 422 a um envelope desfeito, marcando a operação que
+// falhou e as demais como ignoradas.
+func falharEnvelope(w http.ResponseWriter, r *http.Request, resultados []ResultadoOperacao, indice int, status string, err error) {
+	for i := range resultados {
+		resultados[i] = ResultadoOperacao{Status: loteIgnorado}
+	}
+	resultados[indice] = ResultadoOperacao{Status: status, Erro: mensagemErro(r, err)}
+	responderJSON(w, r, http.StatusUnprocessableEntity, RespostaEnvelope{Error: mensagem(r, "lote_desfeito"), Requested: len(resultados), Resultados: resultados})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ModoLeitura guarda se o serviço está em modo somente leitura. O valor inicial
+// vem de READ_ONLY; POST /admin/readonly o altera sem reiniciar o processo,
+// opcionalmente com prazo para voltar ao normal sozinho.
+type ModoLeitura struct {
+	ativo atomic.Bool
+
+	mu     sync.Mutex
+	expira time.Time
+	timer  *time.Timer
+}
+
+var modoLeitura = novoModoLeitura(lerEnvBool("READ_ONLY", false))
+
+func novoModoLeitura(ativo bool) *ModoLeitura {
+	m := &ModoLeitura{}
+	m.ativo.Store(ativo)
+	return m
+}
+
+// definir liga ou desliga o modo. Com duracao > 0 o modo volta ao estado
+// oposto quando ela termina; uma nova chamada cancela o prazo anterior.
+func (m *ModoLeitura) definir(ativo bool, duracao time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer, m.expira = nil, time.Time{}
+	}
+	m.ativo.Store(ativo)
+
+	if duracao > 0 {
+		m.expira = time.Now().Add(duracao)
+		var timer *time.Timer
+		timer = time.AfterFunc(duracao, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.timer != timer {
+				return
+			}
+			m.ativo.Store(!ativo)
+			m.timer, m.expira = nil, time.Time{}
+		})
+		m.timer = timer
+	}
+}
+
+// EstadoModoLeitura é a resposta de /admin/readonly.
+type EstadoModoLeitura struct {
+	ReadOnly  bool       `json:"read_only"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (m *ModoLeitura) estado() EstadoModoLeitura {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	estado := EstadoModoLeitura{ReadOnly: m.ativo.Load()}
+	if !m.expira.IsZero() {
+		expira := m.expira.UTC()
+		estado.ExpiresAt = &expira
+	}
+	return estado
+}
+
+// rotasEscritaLiberadas aceitam métodos de escrita mesmo em modo somente
+// leitura, porque não gravam nada ou porque servem para sair dele.
+var rotasEscritaLiberadas = map[string]bool{
+	"/pessoas/validate": true,
+	"/admin/readonly":   true,
+	"/admin/shutdown":   true,
+}
+
+// somenteLeituraMiddleware recusa com 503 e Retry-After as requisições de
+// escrita enquanto o modo somente leitura está ligado.
+func somenteLeituraMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if modoLeitura.ativo.Load() && !rotasEscritaLiberadas[templateRota(r)] {
+				w.Header().Set("Retry-After", "60")
+				responderErro(w, r, http.StatusServiceUnavailable, mensagem(r, "somente_leitura"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exigirAdmin protege uma rota administrativa com o token de ADMIN_TOKEN,
+// enviado como "Authorization: Bearer <token>". Sem token configurado a rota
+// segue aberta, como antes da existência do token.
+func exigirAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		enviado, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(enviado), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			responderErro(w, r, http.StatusUnauthorized, mensagem(r, "nao_autorizado"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// consultarModoLeitura atende GET /admin/readonly.
+func consultarModoLeitura(w http.ResponseWriter, r *http.Request) {
+	responderJSON(w, r, http.StatusOK, modoLeitura.estado())
+}
+
+// PedidoModoLeitura é o corpo de POST /admin/readonly. Duration, no formato de
+// time.ParseDuration ("30m"), é opcional e desfaz a mudança ao expirar.
+type PedidoModoLeitura struct {
+	ReadOnly *bool  `json:"read_only"`
+	Duration string `json:"duration"`
+}
+
+// alterarModoLeitura atende POST /admin/readonly, ligando ou desligando o modo
+// somente leitura para janelas de manutenção.
+func alterarModoLeitura(w http.ResponseWriter, r *http.Request) {
+	var pedido PedidoModoLeitura
+	if err := json.NewDecoder(r.Body).Decode(&pedido); err != nil {
+		if !corpoExcedido(w, r, err) {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "json_invalido", err))
+		}
+		return
+	}
+	if pedido.ReadOnly == nil {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "read_only_ausente"))
+		return
+	}
+
+	var duracao time.Duration
+	if pedido.Duration != "" {
+		d, err := time.ParseDuration(pedido.Duration)
+		if err != nil || d <= 0 {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "duration_invalida"))
+			return
+		}
+		duracao = d
+	}
+
+	modoLeitura.definir(*pedido.ReadOnly, duracao)
+	responderJSON(w, r, http.StatusOK, modoLeitura.estado())
+}
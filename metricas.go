@@ -0,0 +1,18 @@
+package main
+
+import (
+	"sync/atomic"
+)
+
+// Contadores informados por /debug/db (ENABLE_DEBUG_DB=true).
+var (
+	metricaFilaBanco atomic.Int64
+	metricaUsoBanco  atomic.Int64
+
+	metricaFilaLote atomic.Int64
+	metricaUsoLote  atomic.Int64
+
+	metricaWebhookEntregues   atomic.Int64
+	metricaWebhookFalhas      atomic.Int64
+	metricaWebhookDescartados atomic.Int64
+)
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// origemPermitida verifica se origem casa com algum padrão da lista. Um padrão
+// pode ser "*", uma origem exata ("https://app.exemplo.com") ou um curinga de
+// subdomínio, com ou sem esquema ("*.exemplo.com", "https://*.exemplo.com").
+func origemPermitida(origem string, padroes []string) bool {
+	u, err := url.Parse(origem)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, padrao := range padroes {
+		if padrao == "*" || strings.EqualFold(padrao, origem) {
+			return true
+		}
+
+		esquema, host, temEsquema := strings.Cut(padrao, "://")
+		if !temEsquema {
+			esquema, host = "", padrao
+		}
+		if esquema != "" && !strings.EqualFold(esquema, u.Scheme) {
+			continue
+		}
+		if dominio, ok := strings.CutPrefix(host, "*."); ok {
+			if strings.HasSuffix(strings.ToLower(u.Host), "."+strings.ToLower(dominio)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cabecalhosExpostos são os cabeçalhos de resposta que o navegador deixa o
+// JavaScript de outra origem ler.
+const cabecalhosExpostos = "X-Total-Count, Content-Range, X-Result-Truncated, X-Matched-Route, Location, Retry-After, ETag"
+
+// corsMiddleware adiciona os cabeçalhos CORS para as origens listadas em
+// CORS_ALLOWED_ORIGINS. A origem da requisição é devolvida explicitamente (em
+// vez de "*") para que CORS_ALLOW_CREDENTIALS funcione; origens fora da lista
+// não recebem cabeçalho algum e o navegador bloqueia a resposta. O padrão "*"
+// não pode ser combinado com CORS_ALLOW_CREDENTIALS: qualquer site poderia ler
+// respostas autenticadas, então o servidor se recusa a subir.
+func corsMiddleware(next http.Handler) http.Handler {
+	origens := lerEnvLista("CORS_ALLOWED_ORIGINS")
+	credenciais := lerEnvBool("CORS_ALLOW_CREDENTIALS", false)
+	if credenciais && slices.Contains(origens, "*") {
+		log.Fatal("CORS_ALLOW_CREDENTIALS=true não pode ser usado com \"*\" em CORS_ALLOWED_ORIGINS; liste as origens")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origem := r.Header.Get("Origin")
+		if origem == "" || len(origens) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !origemPermitida(origem, origens) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origem)
+		w.Header().Set("Access-Control-Expose-Headers", cabecalhosExpostos)
+		if credenciais {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Encoding, Authorization, Prefer, If-None-Match")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// chaveRequestID é a chave do id da requisição no contexto.
+type chaveRequestID struct{}
+
+// requestID devolve o id associado à requisição pelo requestIDMiddleware.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(chaveRequestID{}).(string)
+	return id
+}
+
+// gerarRequestID cria um id aleatório de 16 bytes em hexadecimal.
+func gerarRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDValido limita os ids recebidos a 128 caracteres de um conjunto
+// seguro para logs, cabeçalhos e a coluna request_id (VARCHAR(128)) da
+// auditoria; um id maior faria toda escrita falhar no modo estrito do MySQL.
+var requestIDValido = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDMiddleware associa um id a cada requisição e o devolve na resposta.
+// O cabeçalho usado é configurável por REQUEST_ID_HEADER (X-Request-Id por
+// padrão); quando o cliente ou um proxy já envia um id válido nesse cabeçalho,
+// ele é reaproveitado para manter o rastreamento distribuído. Ids fora de
+// requestIDValido são trocados por um novo.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	cabecalho := lerEnv("REQUEST_ID_HEADER", "X-Request-Id")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(cabecalho)
+		if !requestIDValido.MatchString(id) {
+			id = gerarRequestID()
+		}
+
+		w.Header().Set(cabecalho, id)
+		ctx := context.WithValue(r.Context(), chaveRequestID{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// rotasSaude são atendidas também por HTTP simples, já que as sondas do
+// orquestrador costumam chamar o contêiner diretamente, sem passar pelo proxy.
+var rotasSaude = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/health":  true,
+}
+
+// httpsMiddleware, ativado por FORCE_HTTPS, redireciona com 308 as requisições
+// feitas por HTTP para o mesmo endereço em HTTPS e envia Strict-Transport-Security
+// nas respostas HTTPS. Atrás de um proxy que termina o TLS, o esquema original
+// vem de X-Forwarded-Proto. HSTS_MAX_AGE (1 ano) define a validade do HSTS.
+func httpsMiddleware(next http.Handler) http.Handler {
+	if !lerEnvBool("FORCE_HTTPS", false) {
+		return next
+	}
+	hsts := "max-age=" + strconv.Itoa(int(lerEnvDuracao("HSTS_MAX_AGE", 365*24*time.Hour).Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		esquema := "http"
+		if r.TLS != nil {
+			esquema = "https"
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			esquema, _, _ = strings.Cut(proto, ",")
+			esquema = strings.ToLower(strings.TrimSpace(esquema))
+		}
+
+		if esquema == "https" {
+			w.Header().Set("Strict-Transport-Security", hsts)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rotasSaude[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+type chaveCaminhoOriginal struct{}
+
+// caminhoOriginal devolve o caminho da requisição antes de removerPrefixoMiddleware,
+// para logs; sem prefixo removido, é o próprio r.URL.Path.
+func caminhoOriginal(r *http.Request) string {
+	if caminho, ok := r.Context().Value(chaveCaminhoOriginal{}).(string); ok {
+		return caminho
+	}
+	return r.URL.Path
+}
+
+// removerPrefixoMiddleware tira STRIP_PREFIX (por exemplo "/service") do início
+// do caminho antes do roteamento, para proxies que repassam a requisição sem
+// remover o próprio prefixo. Caminhos sem o prefixo seguem inalterados. O
+// caminho original fica disponível em caminhoOriginal.
+func removerPrefixoMiddleware(next http.Handler) http.Handler {
+	prefixo := strings.TrimSuffix(lerEnv("STRIP_PREFIX", ""), "/")
+	if prefixo == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caminho, ok := strings.CutPrefix(r.URL.Path, prefixo)
+		if !ok || (caminho != "" && caminho[0] != '/') {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if caminho == "" {
+			caminho = "/"
+		}
+
+		ctx := context.WithValue(r.Context(), chaveCaminhoOriginal{}, r.URL.Path)
+		r2 := r.WithContext(ctx)
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = caminho
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// inicializado passa a valer true quando o banco está conectado e migrado e o
+// servidor pode atender requisições.
+var inicializado atomic.Bool
+
+// inicializacaoMiddleware responde 503 com Retry-After enquanto a inicialização
+// não termina, para que requisições precoces não encontrem o banco pela metade.
+// /healthz fica de fora: o processo está vivo, apenas ainda não está pronto.
+func inicializacaoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !inicializado.Load() && r.URL.Path != "/healthz" {
+			w.Header().Set("Retry-After", "5")
+			responderErro(w, r, http.StatusServiceUnavailable, mensagem(r, "inicializando"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limiteQueryMiddleware recusa com 414 as requisições cuja query string excede
+// MAX_QUERY_LENGTH bytes (8 KiB por padrão), limitando o custo de interpretar
+// filtros muito longos.
+func limiteQueryMiddleware(next http.Handler) http.Handler {
+	limite := lerEnvInt("MAX_QUERY_LENGTH", 8<<10)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.RawQuery) > limite {
+			responderErro(w, r, http.StatusRequestURITooLong, mensagem(r, "query_longa"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corpoGzip fecha o leitor gzip e o corpo original juntos.
+type corpoGzip struct {
+	*gzip.Reader
+	original io.Closer
+}
+
+func (c corpoGzip) Close() error {
+	c.Reader.Close()
+	return c.original.Close()
+}
+
+// descompactarCorpoMiddleware aceita corpos enviados com Content-Encoding: gzip,
+// trocando r.Body por um leitor que os descompacta, para que uploads grandes
+// (como os de /pessoas/import) gastem menos banda. Um cabeçalho gzip inválido
+// é recusado com 400; outras codificações, com 415. O corpo descompactado é
+// limitado a MAX_BODY_BYTES (10 MiB), para que uma "bomba" gzip de poucos KB não
+// cresça sem limite; os handlers respondem 413 ao passar dele (corpoExcedido).
+func descompactarCorpoMiddleware(next http.Handler) http.Handler {
+	limite := int64(lerEnvInt("MAX_BODY_BYTES", 10<<20))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+		case "", "identity":
+			next.ServeHTTP(w, r)
+			return
+		case "gzip", "x-gzip":
+		default:
+			responderErro(w, r, http.StatusUnsupportedMediaType, mensagem(r, "content_encoding_invalido"))
+			return
+		}
+
+		leitor, err := gzip.NewReader(r.Body)
+		if err != nil {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "corpo_gzip_invalido", err))
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.Body = http.MaxBytesReader(w, corpoGzip{Reader: leitor, original: r.Body}, limite)
+		r2.Header.Del("Content-Encoding")
+		r2.Header.Del("Content-Length")
+		r2.ContentLength = -1
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// corpoExcedido responde 413 quando err vem de um corpo maior que
+// MAX_BODY_BYTES, devolvendo true; nos demais casos não responde nada.
+func corpoExcedido(w http.ResponseWriter, r *http.Request, err error) bool {
+	var excedido *http.MaxBytesError
+	if !errors.As(err, &excedido) {
+		return false
+	}
+	responderErro(w, r, http.StatusRequestEntityTooLarge, mensagem(r, "corpo_excedido", excedido.Limit))
+	return true
+}
+
+// rotasSemTimeout são as rotas de streaming, que mantêm a conexão aberta por
+// tempo indeterminado (ou proporcional ao tamanho da tabela) e não recebem prazo.
+var rotasSemTimeout = map[string]bool{
+	"/pessoas/events": true,
+	"/pessoas/export": true,
+	"/pessoas/stream": true,
+}
+
+// lerTimeoutsRotas interpreta REQUEST_TIMEOUT_ROUTES, uma lista de pares
+// "[MÉTODO ]template=duração" no formato de RATE_LIMIT_ROUTES, como
+// "GET /pessoas/export=5m,/pessoas/bulk=1m". Duração 0 tira o prazo da rota.
+func lerTimeoutsRotas() map[string]time.Duration {
+	prazos := make(map[string]time.Duration)
+	for _, item := range lerEnvLista("REQUEST_TIMEOUT_ROUTES") {
+		rota, valor, ok := strings.Cut(item, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(valor))
+		if !ok || err != nil || d < 0 {
+			log.Fatalf("Valor inválido em REQUEST_TIMEOUT_ROUTES: %q", item)
+		}
+		prazos[strings.Join(strings.Fields(rota), " ")] = d
+	}
+	return prazos
+}
+
+// timeoutMiddleware define o prazo da requisição no contexto, de modo que as
+// consultas ao banco sejam canceladas quando ele expira. O prazo da rota vem de
+// REQUEST_TIMEOUT_ROUTES; sem ele, as rotasSemTimeout não têm prazo e as demais
+// usam REQUEST_TIMEOUT (10s). O cliente pode pedir outro em milissegundos no
+// cabeçalho X-Request-Timeout, limitado a MAX_REQUEST_TIMEOUT (30s). Como o mux
+// reaplica os middlewares a cada requisição, a configuração é lida aqui, uma
+// única vez, e compartilhada pelo middleware devolvido.
+func timeoutMiddleware() mux.MiddlewareFunc {
+	padrao := lerEnvDuracao("REQUEST_TIMEOUT", 10*time.Second)
+	maximo := lerEnvDuracao("MAX_REQUEST_TIMEOUT", 30*time.Second)
+	prazosRotas := lerTimeoutsRotas()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rota := templateRota(r)
+			prazo, definido := prazosRotas[r.Method+" "+rota]
+			if !definido {
+				prazo, definido = prazosRotas[rota]
+			}
+			if !definido {
+				if rotasSemTimeout[rota] {
+					next.ServeHTTP(w, r)
+					return
+				}
+				prazo = padrao
+			}
+			if valor := r.Header.Get("X-Request-Timeout"); valor != "" {
+				ms, err := strconv.Atoi(valor)
+				if err != nil || ms <= 0 {
+					responderErro(w, r, http.StatusBadRequest, mensagem(r, "timeout_invalido"))
+					return
+				}
+				prazo = min(time.Duration(ms)*time.Millisecond, maximo)
+			}
+			if prazo == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), prazo)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// rotaMiddleware informa em X-Matched-Route o template da rota que atendeu a
+// requisição (como "/pessoas/{id}"), para que os clientes agrupem métricas por
+// rota e não por caminho concreto. Sem rota casada, o cabeçalho é omitido.
+func rotaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rota := templateRota(r); rota != "" {
+			w.Header().Set("X-Matched-Route", rota)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// templateRota devolve o template da rota do mux que atendeu a requisição, ou
+// "" quando nenhuma rota casou.
+func templateRota(r *http.Request) string {
+	rota := mux.CurrentRoute(r)
+	if rota == nil {
+		return ""
+	}
+	template, _ := rota.GetPathTemplate()
+	return template
+}
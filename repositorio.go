@@ -0,0 +1,768 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrPessoaNaoEncontrada indica que não existe pessoa com o id pedido.
+var ErrPessoaNaoEncontrada = errors.New("pessoa não encontrada")
+
+// ErrPessoaDuplicada indica que já existe uma pessoa com o mesmo nome.
+var ErrPessoaDuplicada = errors.New("já existe uma pessoa com esse nome")
+
+// maxIDsPorConsulta limita os ids de uma cláusula IN, configurável por
+// MAX_IDS_PER_QUERY. Filtros com mais ids são recusados; as leituras internas
+// por lista de ids são divididas em várias consultas.
+var maxIDsPorConsulta = lerMaxIDsPorConsulta()
+
+// lerMaxIDsPorConsulta lê MAX_IDS_PER_QUERY (500 por padrão), que precisa ser
+// positivo.
+func lerMaxIDsPorConsulta() int {
+	n := lerEnvInt("MAX_IDS_PER_QUERY", 500)
+	if n < 1 {
+		log.Fatalf("MAX_IDS_PER_QUERY deve ser positivo: %d", n)
+	}
+	return n
+}
+
+// FiltroPessoas reúne os critérios aceitos por PessoaRepository.List.
+type FiltroPessoas struct {
+	MinID         *int
+	MaxID         *int
+	IDs           []int      // no máximo maxIDsPorConsulta
+	NomeExato     string     // vazio não filtra; compara segundo a collation da coluna
+	CriadoApos    *time.Time // criado_em >= CriadoApos
+	CriadoAntes   *time.Time // criado_em < CriadoAntes
+	Ordenacao     string     // uma das colunasOrdenaveis; vazio ordena por id
+	Descendente   bool
+	NulosPrimeiro bool // em colunas anuláveis; por padrão os nulos vêm no fim
+	OrdemIDs      bool // ordena pela posição em IDs, ignorando Ordenacao
+	Limite        int  // 0 não limita
+	Deslocamento  int  // linhas a pular; só vale com Limite
+}
+
+// PessoaRepository separa o acesso aos dados de pessoas dos handlers HTTP.
+type PessoaRepository interface {
+	List(ctx context.Context, filtro FiltroPessoas) ([]Pessoa, error)
+	Count(ctx context.Context, filtro FiltroPessoas) (int, error)
+	CollectionState(ctx context.Context) (EstadoColecao, error)
+	Get(ctx context.Context, id int) (Pessoa, error)
+	GetByUUID(ctx context.Context, id string) (Pessoa, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	Create(ctx context.Context, p Pessoa) (Pessoa, error)
+	CreateIfAbsent(ctx context.Context, p Pessoa) (Pessoa, error)
+	NameExists(ctx context.Context, nome string) (bool, error)
+	CreateMany(ctx context.Context, pessoas []Pessoa) ([]Pessoa, error)
+	Update(ctx context.Context, p Pessoa) (Pessoa, error)
+	UpdateMany(ctx context.Context, pessoas []Pessoa, parcial bool) (map[int]Pessoa, error)
+	Delete(ctx context.Context, id int) (Pessoa, error)
+	Batch(ctx context.Context, operacoes []OperacaoLote) ([]Pessoa, int, error)
+	History(ctx context.Context, id int) ([]RegistroAuditoria, error)
+	Suggest(ctx context.Context, prefixo string, limite int) ([]Sugestao, error)
+	Initials(ctx context.Context) ([]Inicial, error)
+	Duplicates(ctx context.Context) ([]Duplicado, error)
+}
+
+// repositorio é o PessoaRepository usado pelos handlers.
+var repositorio PessoaRepository
+
+// tamanhoLoteInsercao é a quantidade de linhas por comando INSERT em CreateMany.
+const tamanhoLoteInsercao = 100
+
+// colunasPessoa são as colunas lidas por escanearPessoa, nessa ordem.
+const colunasPessoa = "id, uuid, nome, criado_em, atualizado_em"
+
+// escaneavel é satisfeito por *sql.Row e *sql.Rows.
+type escaneavel interface {
+	Scan(dest ...any) error
+}
+
+// escanearPessoa lê uma linha com as colunasPessoa. Um nome NULL, possível em
+// bancos legados sem a restrição NOT NULL, é lido como "" em vez de falhar o
+// Scan; o mesmo vale para o uuid.
+func escanearPessoa(linha escaneavel) (Pessoa, error) {
+	var p Pessoa
+	var id, nome sql.NullString
+	err := linha.Scan(&p.ID, &id, &nome, &p.CriadoEm, &p.AtualizadoEm)
+	p.UUID, p.Nome = id.String, nome.String
+	return p, err
+}
+
+// MySQLPessoaRepository implementa PessoaRepository sobre o MySQL.
+type MySQLPessoaRepository struct {
+	db         *sql.DB
+	leitura    *sql.DB // consultas fora de transação; pode ser uma réplica
+	isolamento map[string]sql.IsolationLevel
+}
+
+// NewMySQLPessoaRepository cria um repositório que grava pela conexão db e lê
+// por leitura (que pode ser a mesma), com os níveis de isolamento de
+// lerIsolamento. Transações e NameExists, que antecede uma criação, usam sempre
+// db.
+func NewMySQLPessoaRepository(db, leitura *sql.DB) *MySQLPessoaRepository {
+	return &MySQLPessoaRepository{db: db, leitura: leitura, isolamento: lerIsolamento()}
+}
+
+// List devolve as pessoas que atendem ao filtro, desempatando a ordenação pelo
+// id para que a paginação seja estável.
+func (repo *MySQLPessoaRepository) List(ctx context.Context, filtro FiltroPessoas) ([]Pessoa, error) {
+	where, args := condicoesFiltro(filtro)
+	query := sqlPessoas("SELECT " + colunasPessoa + " FROM {tabela}" + where)
+
+	coluna := filtro.Ordenacao
+	if coluna == "" {
+		coluna = "id"
+	}
+	if !colunasOrdenaveis[coluna] {
+		return nil, fmt.Errorf("coluna de ordenação inválida: %q", coluna)
+	}
+	direcao := "ASC"
+	if filtro.Descendente {
+		direcao = "DESC"
+	}
+	if filtro.OrdemIDs && len(filtro.IDs) > 0 {
+		// FIELD é específico do MySQL: devolve a posição do id na lista.
+		marcadores, argsIDs := marcadoresIDs(filtro.IDs)
+		query += " ORDER BY FIELD(id, " + marcadores + ")"
+		args = append(args, argsIDs...)
+	} else {
+		// O MySQL não tem NULLS FIRST/LAST e põe os nulos no início em ASC e no
+		// fim em DESC; ordenar antes por "coluna IS NULL" fixa a posição deles.
+		query += " ORDER BY "
+		if colunasAnulaveis[coluna] {
+			if filtro.NulosPrimeiro {
+				query += coluna + " IS NULL DESC, "
+			} else {
+				query += coluna + " IS NULL, "
+			}
+		}
+		query += coluna + " " + direcao
+		if coluna != "id" {
+			query += ", id " + direcao
+		}
+	}
+
+	if filtro.Limite > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filtro.Limite, filtro.Deslocamento)
+	}
+
+	rows, err := repo.leitura.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Não nulo, para que uma lista vazia seja serializada como [] e não null.
+	pessoas := []Pessoa{}
+	for rows.Next() {
+		// Se o cliente desconectou, para de ler já; o defer fecha rows e libera
+		// a conexão sem consumir o resto do resultado.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p, err := escanearPessoa(rows)
+		if err != nil {
+			return nil, err
+		}
+		pessoas = append(pessoas, p)
+	}
+	// Um cancelamento também pode encerrar rows por dentro; nesse caso a lista
+	// está incompleta e não é devolvida.
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pessoas, nil
+}
+
+// condicoesFiltro monta a cláusula WHERE (com o espaço inicial, ou vazia) e os
+// argumentos correspondentes aos filtros de id.
+func condicoesFiltro(filtro FiltroPessoas) (string, []any) {
+	var condicoes []string
+	var args []any
+
+	if filtro.MinID != nil {
+		condicoes = append(condicoes, "id >= ?")
+		args = append(args, *filtro.MinID)
+	}
+	if filtro.MaxID != nil {
+		condicoes = append(condicoes, "id <= ?")
+		args = append(args, *filtro.MaxID)
+	}
+	if len(filtro.IDs) > 0 {
+		marcadores, argsIDs := marcadoresIDs(filtro.IDs)
+		condicoes = append(condicoes, "id IN ("+marcadores+")")
+		args = append(args, argsIDs...)
+	}
+	if filtro.CriadoApos != nil {
+		condicoes = append(condicoes, "criado_em >= ?")
+		args = append(args, *filtro.CriadoApos)
+	}
+	if filtro.CriadoAntes != nil {
+		condicoes = append(condicoes, "criado_em < ?")
+		args = append(args, *filtro.CriadoAntes)
+	}
+	if filtro.NomeExato != "" {
+		condicoes = append(condicoes, "nome = ?")
+		args = append(args, filtro.NomeExato)
+	}
+	if len(condicoes) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(condicoes, " AND "), args
+}
+
+// Count conta as pessoas que casam com os filtros; ordenação e paginação
+// são ignoradas.
+func (repo *MySQLPessoaRepository) Count(ctx context.Context, filtro FiltroPessoas) (int, error) {
+	where, args := condicoesFiltro(filtro)
+	var total int
+	err := repo.leitura.QueryRowContext(ctx, sqlPessoas("SELECT COUNT(*) FROM {tabela}"+where), args...).Scan(&total)
+	return total, err
+}
+
+// CollectionState lê a versão de escrita da tabela, o contador que
+// confirmarEscrita incrementa em toda transação de escrita.
+func (repo *MySQLPessoaRepository) CollectionState(ctx context.Context) (EstadoColecao, error) {
+	var estado EstadoColecao
+	err := repo.leitura.QueryRowContext(ctx, sqlPessoas("SELECT versao FROM {versao} WHERE id = 1")).Scan(&estado.Versao)
+	return estado, err
+}
+
+// Get busca uma pessoa pelo id, devolvendo ErrPessoaNaoEncontrada se não existir.
+func (repo *MySQLPessoaRepository) Get(ctx context.Context, id int) (Pessoa, error) {
+	return buscarPessoa(ctx, repo.leitura, id, false)
+}
+
+// GetByUUID busca uma pessoa pelo uuid público, devolvendo
+// ErrPessoaNaoEncontrada se não existir.
+func (repo *MySQLPessoaRepository) GetByUUID(ctx context.Context, id string) (Pessoa, error) {
+	p, err := escanearPessoa(repo.leitura.QueryRowContext(ctx, sqlPessoas("SELECT "+colunasPessoa+" FROM {tabela} WHERE uuid = ?"), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Pessoa{}, ErrPessoaNaoEncontrada
+	}
+	return p, err
+}
+
+// Exists informa se há uma pessoa com o id, sem ler a linha.
+func (repo *MySQLPessoaRepository) Exists(ctx context.Context, id int) (bool, error) {
+	var existe bool
+	err := repo.leitura.QueryRowContext(ctx, sqlPessoas("SELECT EXISTS(SELECT 1 FROM {tabela} WHERE id = ?)"), id).Scan(&existe)
+	return existe, err
+}
+
+// consultor é satisfeito por *sql.DB e *sql.Tx.
+type consultor interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// buscarPessoa lê a pessoa pelo id usando q. Com bloquear, a linha fica
+// travada (FOR UPDATE) até o fim da transação.
+func buscarPessoa(ctx context.Context, q consultor, id int, bloquear bool) (Pessoa, error) {
+	query := sqlPessoas("SELECT " + colunasPessoa + " FROM {tabela} WHERE id = ?")
+	if bloquear {
+		query += " FOR UPDATE"
+	}
+
+	p, err := escanearPessoa(q.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Pessoa{}, ErrPessoaNaoEncontrada
+	}
+	return p, err
+}
+
+// inserirPessoa insere p na transação, com um uuid novo, audita e devolve o
+// registro gravado.
+func inserirPessoa(ctx context.Context, tx *sql.Tx, p Pessoa) (Pessoa, error) {
+	resultado, err := tx.ExecContext(ctx, sqlPessoas("INSERT INTO {tabela} (nome, uuid) VALUES (?, ?)"), p.Nome, uuid.NewString())
+	if err != nil {
+		return Pessoa{}, err
+	}
+
+	id, err := resultado.LastInsertId()
+	if err != nil {
+		return Pessoa{}, err
+	}
+
+	nova, err := buscarPessoa(ctx, tx, int(id), false)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	if err := registrarAuditoria(ctx, tx, operacaoInsercao, nova.ID, nil, &nova); err != nil {
+		return Pessoa{}, err
+	}
+	return nova, nil
+}
+
+// Create insere a pessoa e devolve o registro gravado, com id e criado_em.
+func (repo *MySQLPessoaRepository) Create(ctx context.Context, p Pessoa) (Pessoa, error) {
+	tx, err := repo.iniciarTransacao(ctx, transacaoCriacao)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer tx.Rollback()
+
+	nova, err := inserirPessoa(ctx, tx, p)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	return nova, confirmarEscrita(ctx, tx)
+}
+
+// CreateIfAbsent insere a pessoa apenas se ainda não houver outra com o mesmo
+// nome, devolvendo ErrPessoaDuplicada caso contrário. A leitura com FOR UPDATE
+// trava as linhas e lacunas examinadas, impedindo que duas requisições
+// concorrentes criem o mesmo nome; a trava de lacunas exige ao menos
+// repeatable-read, garantido por iniciarTransacaoSeAusente.
+func (repo *MySQLPessoaRepository) CreateIfAbsent(ctx context.Context, p Pessoa) (Pessoa, error) {
+	tx, err := repo.iniciarTransacaoSeAusente(ctx)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer tx.Rollback()
+
+	consulta, args := consultaNomeExistente(p.Nome)
+	var existente int
+	err = tx.QueryRowContext(ctx, consulta+" FOR UPDATE", args...).Scan(&existente)
+	if err == nil {
+		return Pessoa{}, ErrPessoaDuplicada
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Pessoa{}, err
+	}
+
+	nova, err := inserirPessoa(ctx, tx, p)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	return nova, confirmarEscrita(ctx, tx)
+}
+
+// consultaNomeExistente monta a consulta que busca o id de uma pessoa com o
+// nome. nome = ? segue a collation da coluna (sem distinção de caixa no padrão
+// utf8mb4_unicode_ci) e usa o índice; quando a caixa deve distinguir nomes, a
+// comparação binária refina o resultado.
+func consultaNomeExistente(nome string) (string, []any) {
+	consulta, args := "SELECT id FROM {tabela} WHERE nome = ?", []any{nome}
+	if !nomesUnicosSemCaixa {
+		consulta += " AND CAST(nome AS BINARY) = CAST(? AS BINARY)"
+		args = append(args, nome)
+	}
+	return sqlPessoas(consulta + " LIMIT 1"), args
+}
+
+// NameExists informa se já há uma pessoa com o nome, com a mesma comparação de
+// CreateIfAbsent.
+func (repo *MySQLPessoaRepository) NameExists(ctx context.Context, nome string) (bool, error) {
+	consulta, args := consultaNomeExistente(nome)
+	var existente int
+	err := repo.db.QueryRowContext(ctx, consulta, args...).Scan(&existente)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateMany insere as pessoas em lotes dentro de uma única transação, ou todas
+// ou nenhuma, e devolve os registros gravados em ordem de id.
+func (repo *MySQLPessoaRepository) CreateMany(ctx context.Context, pessoas []Pessoa) ([]Pessoa, error) {
+	tx, err := repo.iniciarTransacao(ctx, transacaoLote)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inseridas := make([]Pessoa, 0, len(pessoas))
+	for inicio := 0; inicio < len(pessoas); inicio += tamanhoLoteInsercao {
+		lote := pessoas[inicio:min(inicio+tamanhoLoteInsercao, len(pessoas))]
+
+		marcadores := strings.TrimSuffix(strings.Repeat("(?, ?),", len(lote)), ",")
+		args := make([]any, 0, 2*len(lote))
+		uuids := make([]string, len(lote))
+		for i, p := range lote {
+			uuids[i] = uuid.NewString()
+			args = append(args, p.Nome, uuids[i])
+		}
+
+		if _, err := tx.ExecContext(ctx, sqlPessoas("INSERT INTO {tabela} (nome, uuid) VALUES ")+marcadores, args...); err != nil {
+			return nil, err
+		}
+
+		// Os ids de um INSERT com várias linhas não são necessariamente
+		// consecutivos (innodb_autoinc_lock_mode=2), então o lote é auditado e
+		// relido pelos uuids que acabou de gerar.
+		if err := registrarAuditoriaInsercoes(ctx, tx, uuids); err != nil {
+			return nil, err
+		}
+		gravadas, err := buscarPessoasPorUUIDs(ctx, tx, uuids)
+		if err != nil {
+			return nil, err
+		}
+		inseridas = append(inseridas, gravadas...)
+	}
+
+	return inseridas, confirmarEscrita(ctx, tx)
+}
+
+// buscarPessoasPorUUIDs lê na transação, em ordem de id, as pessoas com os
+// uuids informados.
+func buscarPessoasPorUUIDs(ctx context.Context, tx *sql.Tx, uuids []string) ([]Pessoa, error) {
+	args := make([]any, len(uuids))
+	for i, u := range uuids {
+		args[i] = u
+	}
+	rows, err := tx.QueryContext(ctx, sqlPessoas("SELECT "+colunasPessoa+" FROM {tabela} WHERE uuid IN ("+
+		strings.TrimSuffix(strings.Repeat("?,", len(uuids)), ",")+") ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pessoas := make([]Pessoa, 0, len(uuids))
+	for rows.Next() {
+		p, err := escanearPessoa(rows)
+		if err != nil {
+			return nil, err
+		}
+		pessoas = append(pessoas, p)
+	}
+	return pessoas, rows.Err()
+}
+
+// Update grava o nome da pessoa p.ID e devolve o registro atualizado.
+func (repo *MySQLPessoaRepository) Update(ctx context.Context, p Pessoa) (Pessoa, error) {
+	tx, err := repo.iniciarTransacao(ctx, transacaoAtualizacao)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer tx.Rollback()
+
+	atualizada, err := atualizarPessoa(ctx, tx, p)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	return atualizada, confirmarEscrita(ctx, tx)
+}
+
+// atualizarPessoa grava o nome de p na transação, travando a linha antes, audita
+// e devolve o registro gravado.
+func atualizarPessoa(ctx context.Context, tx *sql.Tx, p Pessoa) (Pessoa, error) {
+	anterior, err := buscarPessoa(ctx, tx, p.ID, true)
+	if err != nil {
+		return Pessoa{}, err
+	}
+
+	// criado_em nunca é escrito por uma atualização; só atualizado_em muda.
+	if _, err := tx.ExecContext(ctx, sqlPessoas("UPDATE {tabela} SET nome = ?, atualizado_em = CURRENT_TIMESTAMP WHERE id = ?"), p.Nome, p.ID); err != nil {
+		return Pessoa{}, err
+	}
+
+	atualizada, err := buscarPessoa(ctx, tx, p.ID, false)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	if err := registrarAuditoria(ctx, tx, operacaoAtualizacao, p.ID, &anterior, &atualizada); err != nil {
+		return Pessoa{}, err
+	}
+	return atualizada, nil
+}
+
+// buscarPessoasPorIDs lê, travando para atualização, as pessoas cujos ids estão
+// em ids.
+func buscarPessoasPorIDs(ctx context.Context, tx *sql.Tx, ids []int) (map[int]Pessoa, error) {
+	encontradas := make(map[int]Pessoa, len(ids))
+	for len(ids) > 0 {
+		parte := ids[:min(len(ids), maxIDsPorConsulta)]
+		ids = ids[len(parte):]
+
+		marcadores, args := marcadoresIDs(parte)
+		rows, err := tx.QueryContext(ctx, sqlPessoas("SELECT "+colunasPessoa+" FROM {tabela} WHERE id IN ("+marcadores+") FOR UPDATE"), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			p, err := escanearPessoa(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			encontradas[p.ID] = p
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return encontradas, nil
+}
+
+// marcadoresIDs monta os marcadores de um IN com os ids e seus argumentos.
+func marcadoresIDs(ids []int) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
+
+// UpdateMany atualiza os nomes das pessoas numa única transação e devolve as
+// versões gravadas, por id. Se algum id não existir e parcial for falso, nada é
+// gravado e o erro é ErrLoteIncompleto; o mapa devolvido indica então quais ids
+// existem. Com parcial, os ids inexistentes são apenas omitidos do mapa.
+func (repo *MySQLPessoaRepository) UpdateMany(ctx context.Context, pessoas []Pessoa, parcial bool) (map[int]Pessoa, error) {
+	tx, err := repo.iniciarTransacao(ctx, transacaoLote)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, len(pessoas))
+	for i, p := range pessoas {
+		ids[i] = p.ID
+	}
+
+	anteriores, err := buscarPessoasPorIDs(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(anteriores) < len(pessoas) && !parcial {
+		return anteriores, ErrLoteIncompleto
+	}
+
+	stmt, err := tx.PrepareContext(ctx, sqlPessoas("UPDATE {tabela} SET nome = ?, atualizado_em = CURRENT_TIMESTAMP WHERE id = ?"))
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for _, p := range pessoas {
+		if _, ok := anteriores[p.ID]; !ok {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, p.Nome, p.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	existentes := make([]int, 0, len(anteriores))
+	for id := range anteriores {
+		existentes = append(existentes, id)
+	}
+	atualizadas, err := buscarPessoasPorIDs(ctx, tx, existentes)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, atualizada := range atualizadas {
+		anterior := anteriores[id]
+		if err := registrarAuditoria(ctx, tx, operacaoAtualizacao, id, &anterior, &atualizada); err != nil {
+			return nil, err
+		}
+	}
+	return atualizadas, confirmarEscrita(ctx, tx)
+}
+
+// Delete remove a pessoa pelo id e devolve o registro removido, lido na mesma
+// transação, ou ErrPessoaNaoEncontrada se o id não existir.
+func (repo *MySQLPessoaRepository) Delete(ctx context.Context, id int) (Pessoa, error) {
+	tx, err := repo.iniciarTransacao(ctx, transacaoRemocao)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	defer tx.Rollback()
+
+	removida, err := removerPessoaTx(ctx, tx, id)
+	if err != nil {
+		return Pessoa{}, err
+	}
+	return removida, confirmarEscrita(ctx, tx)
+}
+
+// removerPessoaTx remove a pessoa na transação, travando a linha antes, audita
+// e devolve o registro removido, ou ErrPessoaNaoEncontrada se o id não existir.
+func removerPessoaTx(ctx context.Context, tx *sql.Tx, id int) (Pessoa, error) {
+	anterior, err := buscarPessoa(ctx, tx, id, true)
+	if err != nil {
+		return Pessoa{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, sqlPessoas("DELETE FROM {tabela} WHERE id = ?"), id); err != nil {
+		return Pessoa{}, err
+	}
+	if err := registrarAuditoria(ctx, tx, operacaoRemocao, id, &anterior, nil); err != nil {
+		return Pessoa{}, err
+	}
+	return anterior, nil
+}
+
+// History devolve as alterações registradas para o id, da mais antiga para a
+// mais recente.
+func (repo *MySQLPessoaRepository) History(ctx context.Context, id int) ([]RegistroAuditoria, error) {
+	rows, err := repo.leitura.QueryContext(ctx, sqlPessoas(`SELECT id, pessoa_id, operacao, valor_anterior, valor_novo, request_id, registrado_em
+		FROM {auditoria} WHERE pessoa_id = ? ORDER BY id`), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	registros := []RegistroAuditoria{}
+	for rows.Next() {
+		var registro RegistroAuditoria
+		var anterior, novo []byte
+		var idRequisicao sql.NullString
+		err := rows.Scan(&registro.ID, &registro.PessoaID, &registro.Operacao, &anterior, &novo, &idRequisicao, &registro.RegistradoEm)
+		if err != nil {
+			return nil, err
+		}
+		registro.ValorAnterior = anterior
+		registro.ValorNovo = novo
+		registro.RequestID = idRequisicao.String
+		registros = append(registros, registro)
+	}
+	return registros, rows.Err()
+}
+
+// escaparLike escapa os curingas do LIKE para que o texto seja comparado
+// literalmente.
+func escaparLike(texto string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(texto)
+}
+
+// Suggest devolve até limite pessoas cujo nome começa com prefixo, em ordem
+// alfabética. Por ser um LIKE de prefixo, a busca usa o índice sobre nome.
+func (repo *MySQLPessoaRepository) Suggest(ctx context.Context, prefixo string, limite int) ([]Sugestao, error) {
+	rows, err := repo.leitura.QueryContext(ctx, sqlPessoas("SELECT id, nome FROM {tabela} WHERE nome LIKE ? ORDER BY nome, id LIMIT ?"),
+		escaparLike(prefixo)+"%", limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sugestoes := []Sugestao{}
+	for rows.Next() {
+		var s Sugestao
+		var nome sql.NullString
+		if err := rows.Scan(&s.ID, &nome); err != nil {
+			return nil, err
+		}
+		s.Nome = nome.String
+		sugestoes = append(sugestoes, s)
+	}
+	return sugestoes, rows.Err()
+}
+
+// Batch executa as operações em ordem numa única transação e devolve, para
+// cada uma, a pessoa criada, atualizada ou removida (zero quando a remoção não
+// encontrou o id, que não é erro, como em Delete). Na primeira falha nada é
+// gravado e o índice da operação que falhou acompanha o erro; sem falha o
+// índice é -1.
+func (repo *MySQLPessoaRepository) Batch(ctx context.Context, operacoes []OperacaoLote) ([]Pessoa, int, error) {
+	tx, err := repo.iniciarTransacao(ctx, transacaoLote)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer tx.Rollback()
+
+	pessoas := make([]Pessoa, len(operacoes))
+	for i, op := range operacoes {
+		switch op.Metodo {
+		case metodoCriacao:
+			pessoas[i], err = inserirPessoa(ctx, tx, *op.Corpo)
+		case metodoAtualizacao:
+			p := *op.Corpo
+			p.ID = op.ID
+			pessoas[i], err = atualizarPessoa(ctx, tx, p)
+		case metodoRemocao:
+			pessoas[i], err = removerPessoaTx(ctx, tx, op.ID)
+			if errors.Is(err, ErrPessoaNaoEncontrada) {
+				err = nil
+			}
+		default:
+			err = fmt.Errorf("método de lote desconhecido: %q", op.Metodo)
+		}
+		if err != nil {
+			return nil, i, err
+		}
+	}
+	return pessoas, -1, confirmarEscrita(ctx, tx)
+}
+
+// Initials conta as pessoas por letra inicial do nome, em ordem alfabética.
+// Iniciais acentuadas contam na letra sem acento (José em J); o agrupamento do
+// banco já junta maiúsculas e minúsculas, e a dobra final garante o mesmo
+// resultado com qualquer collation.
+func (repo *MySQLPessoaRepository) Initials(ctx context.Context) ([]Inicial, error) {
+	rows, err := repo.leitura.QueryContext(ctx, sqlPessoas(`SELECT UPPER(LEFT(nome, 1)) AS letra, COUNT(*)
+		FROM {tabela} WHERE nome <> '' GROUP BY letra ORDER BY letra`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contagens := make(map[string]int)
+	for rows.Next() {
+		var letra string
+		var total int
+		if err := rows.Scan(&letra, &total); err != nil {
+			return nil, err
+		}
+		contagens[strings.ToUpper(dobrarTermo(letra))] += total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	iniciais := make([]Inicial, 0, len(contagens))
+	for letra, total := range contagens {
+		iniciais = append(iniciais, Inicial{Letter: letra, Count: total})
+	}
+	sort.Slice(iniciais, func(i, j int) bool { return iniciais[i].Letter < iniciais[j].Letter })
+	return iniciais, nil
+}
+
+// Duplicates agrupa as pessoas que compartilham o nome, na mesma comparação de
+// consultaNomeExistente, em ordem alfabética e com os ids em ordem crescente.
+// O agrupamento fica todo com o banco: pela collation da coluna, que também
+// ignora acentos em utf8mb4_unicode_ci ("João" e "Joao"), ou pelos bytes quando
+// a caixa distingue nomes. Cada grupo é identificado pelo seu menor id. Nomes
+// NULL de bancos legados ficam de fora: não são um nome repetido, e o MIN(nome)
+// do grupo deles não caberia no string do Scan.
+func (repo *MySQLPessoaRepository) Duplicates(ctx context.Context) ([]Duplicado, error) {
+	chave, chavePessoa := "nome", "p.nome"
+	if !nomesUnicosSemCaixa {
+		chave, chavePessoa = "CAST(nome AS BINARY)", "CAST(p.nome AS BINARY)"
+	}
+	rows, err := repo.leitura.QueryContext(ctx, sqlPessoas(`SELECT p.id, g.grupo, g.exibido FROM {tabela} p
+		JOIN (SELECT `+chave+` AS chave, MIN(id) AS grupo, MIN(nome) AS exibido FROM {tabela}
+			WHERE nome IS NOT NULL GROUP BY chave HAVING COUNT(*) > 1) g ON g.chave = `+chavePessoa+`
+		ORDER BY g.exibido, g.grupo, p.id`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicados := []Duplicado{}
+	ultimo := 0
+	for rows.Next() {
+		var id, grupo int
+		var nome string
+		if err := rows.Scan(&id, &grupo, &nome); err != nil {
+			return nil, err
+		}
+		if grupo != ultimo {
+			ultimo = grupo
+			duplicados = append(duplicados, Duplicado{Nome: nome})
+		}
+		duplicados[len(duplicados)-1].IDs = append(duplicados[len(duplicados)-1].IDs, id)
+	}
+	return duplicados, rows.Err()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestUpdatePreservaCriadoEm garante que uma atualização não escreve criado_em:
+// o UPDATE só toca nome e atualizado_em, e o registro devolvido mantém a data de
+// criação lida antes da alteração.
+func TestUpdatePreservaCriadoEm(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	criadoEm := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	atualizadoEm := criadoEm.Add(48 * time.Hour)
+	colunas := []string{"id", "uuid", "nome", "criado_em", "atualizado_em"}
+	selecao := regexp.QuoteMeta(sqlPessoas("SELECT " + colunasPessoa + " FROM {tabela} WHERE id = ?"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selecao + " FOR UPDATE").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(colunas).AddRow(7, "u-7", "Ana", criadoEm, nil))
+	mock.ExpectExec("^"+regexp.QuoteMeta(sqlPessoas("UPDATE {tabela} SET nome = ?, atualizado_em = CURRENT_TIMESTAMP WHERE id = ?"))+"$").
+		WithArgs("Ana Maria", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(selecao + "$").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(colunas).AddRow(7, "u-7", "Ana Maria", criadoEm, atualizadoEm))
+	mock.ExpectExec(regexp.QuoteMeta(sqlPessoas("INSERT INTO {auditoria}"))).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlPessoas("UPDATE {versao} SET versao = versao + 1"))).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewMySQLPessoaRepository(db, db)
+	atualizada, err := repo.Update(context.Background(), Pessoa{ID: 7, Nome: "Ana Maria"})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if atualizada.CriadoEm == nil || !atualizada.CriadoEm.Equal(criadoEm) {
+		t.Errorf("criado_em = %v, esperado %v", atualizada.CriadoEm, criadoEm)
+	}
+	if atualizada.AtualizadoEm == nil || !atualizada.AtualizadoEm.Equal(atualizadoEm) {
+		t.Errorf("atualizado_em = %v, esperado %v", atualizada.AtualizadoEm, atualizadoEm)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// contextoCancelavel cancela o contexto na n-ésima consulta a Err, simulando um
+// cliente que desconecta no meio da leitura das linhas.
+type contextoCancelavel struct {
+	context.Context
+	cancelar func()
+	n        int
+}
+
+func (c *contextoCancelavel) Err() error {
+	if c.n--; c.n == 0 {
+		c.cancelar()
+	}
+	return c.Context.Err()
+}
+
+// TestListCanceladoNoMeio garante que List para de ler quando o cliente
+// desconecta e devolve context.Canceled sem uma lista parcial.
+func TestListCanceladoNoMeio(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	criadoEm := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	linhas := sqlmock.NewRows([]string{"id", "uuid", "nome", "criado_em", "atualizado_em"})
+	for id := 1; id <= 5; id++ {
+		linhas.AddRow(id, "u", "Pessoa", criadoEm, nil)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(sqlPessoas("SELECT " + colunasPessoa + " FROM {tabela}"))).WillReturnRows(linhas)
+
+	base, cancelar := context.WithCancel(context.Background())
+	defer cancelar()
+	ctx := &contextoCancelavel{Context: base, cancelar: cancelar, n: 3}
+
+	pessoas, err := NewMySQLPessoaRepository(db, db).List(ctx, FiltroPessoas{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("erro = %v, esperado context.Canceled", err)
+	}
+	if pessoas != nil {
+		t.Errorf("lista parcial devolvida: %d pessoas", len(pessoas))
+	}
+}
+
+// TestNaoEncontrada garante que Update e Delete de um id inexistente devolvem
+// ErrPessoaNaoEncontrada. A decisão vem da leitura com FOR UPDATE, sem depender
+// de RowsAffected, e a transação é desfeita.
+func TestNaoEncontrada(t *testing.T) {
+	operacoes := map[string]func(PessoaRepository) error{
+		"Update": func(repo PessoaRepository) error {
+			_, err := repo.Update(context.Background(), Pessoa{ID: 42, Nome: "Ana"})
+			return err
+		},
+		"Delete": func(repo PessoaRepository) error {
+			_, err := repo.Delete(context.Background(), 42)
+			return err
+		},
+	}
+	for nome, operacao := range operacoes {
+		t.Run(nome, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(sqlPessoas("SELECT " + colunasPessoa + " FROM {tabela} WHERE id = ? FOR UPDATE"))).
+				WithArgs(42).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "nome", "criado_em", "atualizado_em"}))
+			mock.ExpectRollback()
+
+			if err := operacao(NewMySQLPessoaRepository(db, db)); !errors.Is(err, ErrPessoaNaoEncontrada) {
+				t.Errorf("erro = %v, esperado ErrPessoaNaoEncontrada", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -1,30 +1,191 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 // Pessoa representa um indivíduo no sistema.
+//
+// ID e Nome estão sempre presentes no JSON (um id 0 é serializado como tal).
+// ID é a chave interna; UUID, gerado na criação, é o identificador público, que
+// não revela quantos registros existem nem pode ser adivinhado.
+// Os campos opcionais são ponteiros com omitempty e somem da resposta quando não
+// têm valor, em vez de aparecerem como null:
+//   - CriadoEm: atribuído pelo banco; ausente em corpos enviados pelo cliente.
+//   - AtualizadoEm: atribuído pelo banco a cada atualização; ausente enquanto a
+//     pessoa nunca foi atualizada.
+//   - NomeLength: derivado, não gravado; presente só com ?include=nome_length.
+//   - DisplayName: derivado de NomeExibicao; presente só com ?include=display_name.
+//
+// As datas saem em RFC 3339, ou em segundos Unix com ?time_format=unix; outro
+// valor de time_format é recusado com 400. Como são do banco, os valores
+// enviados pelo cliente nesses campos são ignorados.
 type Pessoa struct {
-	ID   int    `json:"id"`
-	Nome string `json:"nome"`
+	ID           int       `json:"id"`
+	UUID         string    `json:"uuid,omitempty"`
+	Nome         string    `json:"nome"`
+	CriadoEm     *Instante `json:"criado_em,omitempty"`
+	AtualizadoEm *Instante `json:"atualizado_em,omitempty"`
+	NomeLength   int       `json:"nome_length,omitempty"`
+	DisplayName  string    `json:"display_name,omitempty"`
+}
+
+// NomeExibicao monta o nome usado pelas interfaces para apresentar a pessoa.
+// Por ora é apenas o nome; quando houver outros campos (como e-mail), eles
+// entram aqui sem mudar o esquema.
+func (p Pessoa) NomeExibicao() string {
+	return p.Nome
+}
+
+// colunasOrdenaveis lista as colunas aceitas no parâmetro sort de listarPessoas.
+var colunasOrdenaveis = map[string]bool{
+	"id":            true,
+	"nome":          true,
+	"criado_em":     true,
+	"atualizado_em": true,
+}
+
+// colunasAnulaveis são as colunas ordenáveis que podem ser NULL; nelas o
+// parâmetro nulls de listarPessoas decide onde os nulos ficam.
+var colunasAnulaveis = map[string]bool{
+	"atualizado_em": true,
+}
+
+// ordemPadraoDescendente marca as colunas que, sem order na requisição, são
+// ordenadas da maior para a menor: datas fazem mais sentido das mais recentes
+// para as mais antigas, enquanto nomes e ids seguem crescentes.
+var ordemPadraoDescendente = map[string]bool{
+	"criado_em":     true,
+	"atualizado_em": true,
 }
 
 var dbConn *sql.DB
 
-// configurarDB inicializa a conexão com o banco de dados e cria a tabela se não existir.
+// dbLeitura é a conexão usada pelas leituras do repositório: a réplica de
+// DB_READ_DSN, quando definida, ou o próprio dbConn.
+var dbLeitura *sql.DB
+
+// limiteResultados é o número máximo de pessoas devolvidas por listarPessoas,
+// configurável por MAX_LIST_RESULTS, para que uma listagem sem filtros não
+// carregue a tabela inteira em memória.
+var limiteResultados = lerEnvInt("MAX_LIST_RESULTS", 1000)
+
+// versao identifica a build do serviço; pode ser definida na compilação com
+// -ldflags "-X main.versao=...".
+var versao = "dev"
+
+// montarDSN monta a string de conexão a partir das variáveis DB_*. O charset e a
+// collation padrão (utf8mb4) preservam nomes acentuados, e parseTime faz as
+// colunas DATETIME serem lidas como time.Time em UTC (Loc). A sessão também usa
+// time_zone '+00:00', para que CURRENT_TIMESTAMP grave em UTC e os filtros de
+// data comparem valores no mesmo fuso, qualquer que seja o fuso do servidor.
+func montarDSN() string {
+	cfg := mysql.NewConfig()
+	cfg.User = lerEnv("DB_USER", "username")
+	cfg.Passwd = lerEnv("DB_PASSWORD", "password")
+	cfg.Net = "tcp"
+	cfg.Addr = lerEnv("DB_HOST", "localhost:3306")
+	cfg.DBName = lerEnv("DB_NAME", "jean")
+	cfg.Collation = lerEnv("DB_COLLATION", "utf8mb4_unicode_ci")
+	if nomesUnicosSemCaixa && !strings.HasSuffix(cfg.Collation, "_ci") {
+		log.Fatalf("NAME_UNIQUE_CASE_INSENSITIVE=true exige uma collation _ci, mas DB_COLLATION=%s", cfg.Collation)
+	}
+	cfg.Params = map[string]string{"charset": lerEnv("DB_CHARSET", "utf8mb4"), "time_zone": "'+00:00'"}
+	cfg.ParseTime = true
+	// Sem prazos, um host inacessível faz o Ping e as consultas esperarem
+	// indefinidamente.
+	cfg.Timeout = lerEnvDuracao("DB_CONNECT_TIMEOUT", 5*time.Second)
+	cfg.ReadTimeout = lerEnvDuracao("DB_READ_TIMEOUT", 10*time.Second)
+	cfg.WriteTimeout = lerEnvDuracao("DB_WRITE_TIMEOUT", 10*time.Second)
+	return cfg.FormatDSN()
+}
+
+// montarDSNLeitura ajusta o DSN da réplica de leitura (DB_READ_DSN, no formato
+// do driver) com as mesmas exigências do primário: parseTime ligado, sessão em
+// UTC e prazos padrão quando o DSN não define os seus.
+func montarDSNLeitura(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	cfg.Params["time_zone"] = "'+00:00'"
+	if cfg.Timeout == 0 {
+		cfg.Timeout = lerEnvDuracao("DB_CONNECT_TIMEOUT", 5*time.Second)
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = lerEnvDuracao("DB_READ_TIMEOUT", 10*time.Second)
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// abrirReplicaLeitura conecta dbLeitura à réplica de DB_READ_DSN, ou o aponta
+// para dbConn quando ela não está definida. A réplica pode estar atrasada em
+// relação ao primário: um registro recém-gravado pode demorar a aparecer nas
+// listagens e num GET logo em seguida.
+func abrirReplicaLeitura() {
+	dsn := lerEnv("DB_READ_DSN", "")
+	if dsn == "" {
+		dbLeitura = dbConn
+		return
+	}
+
+	dsn, err := montarDSNLeitura(dsn)
+	if err != nil {
+		log.Fatal("DB_READ_DSN inválido: ", err)
+	}
+	if dbLeitura, err = sql.Open("mysql", dsn); err != nil {
+		log.Fatal("Erro ao abrir a conexão com a réplica de leitura:", err)
+	}
+	if err = dbLeitura.Ping(); err != nil {
+		log.Fatal("Erro ao pingar a réplica de leitura:", err)
+	}
+}
+
+// enderecoEscuta devolve o endereço em que o servidor escuta: LISTEN_ADDR
+// completo (como "127.0.0.1:3333", para não expor a API em todas as interfaces
+// quando há um proxy na mesma máquina) ou, sem ele, ":PORT" (3333 por padrão).
+func enderecoEscuta() string {
+	if endereco := lerEnv("LISTEN_ADDR", ""); endereco != "" {
+		return endereco
+	}
+	return ":" + lerEnv("PORT", "3333")
+}
+
+// configurarDB inicializa a conexão com o banco de dados e cria a tabela se não
+// existir. Com AUTO_MIGRATE=false nenhum DDL é executado: o esquema apenas é
+// conferido, para usuários de banco sem permissão de alterá-lo.
 func configurarDB() {
+	if err := configurarTabela(); err != nil {
+		log.Fatal(err)
+	}
+
 	var err error
-	connStr := "username:password@tcp(localhost:3306)/jean"
-	dbConn, err = sql.Open("mysql", connStr)
+	dbConn, err = sql.Open("mysql", montarDSN())
 	if err != nil {
 		log.Fatal("Erro ao abrir a conexão com o banco de dados:", err)
 	}
@@ -32,103 +193,1079 @@ func configurarDB() {
 	if err = dbConn.Ping(); err != nil {
 		log.Fatal("Erro ao pingar o banco de dados:", err)
 	}
+	abrirReplicaLeitura()
+
+	if !lerEnvBool("AUTO_MIGRATE", true) {
+		if err = verificarEsquema(); err != nil {
+			log.Fatal("Esquema do banco incompleto (AUTO_MIGRATE=false): ", err)
+		}
+		if err = verificarCollationNome(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	_, err = dbConn.Exec(`CREATE TABLE IF NOT EXISTS individuos (
+	_, err = dbConn.Exec(sqlPessoas(`CREATE TABLE IF NOT EXISTS {tabela} (
 		id INT AUTO_INCREMENT PRIMARY KEY,
-		nome VARCHAR(255) NOT NULL
-	)`)
+		uuid CHAR(36) NULL,
+		nome VARCHAR(` + strconv.Itoa(tamanhoMaximoNome) + `) NOT NULL,
+		criado_em DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		atualizado_em DATETIME NULL
+	)`))
 	if err != nil {
 		log.Fatal("Erro ao criar a tabela:", err)
 	}
+
+	err = garantirColuna(tabelaPessoas, "criado_em", "DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP")
+	if err != nil {
+		log.Fatal("Erro ao migrar a tabela:", err)
+	}
+	if err = garantirColuna(tabelaPessoas, "atualizado_em", "DATETIME NULL"); err != nil {
+		log.Fatal("Erro ao migrar a tabela:", err)
+	}
+	if err = garantirColuna(tabelaPessoas, "uuid", "CHAR(36) NULL AFTER id"); err != nil {
+		log.Fatal("Erro ao migrar a tabela:", err)
+	}
+	if err = preencherUUIDs(); err != nil {
+		log.Fatal("Erro ao migrar a tabela:", err)
+	}
+	if err = ajustarTamanhoNome(); err != nil {
+		log.Fatal("Erro ao migrar a tabela:", err)
+	}
+	if err = verificarCollationNome(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Os nomes dos índices precisam acompanhar indicesEsperados.
+	if err = garantirIndice(tabelaPessoas, "idx_"+tabelaPessoas+"_criado_em", "criado_em"); err != nil {
+		log.Fatal("Erro ao criar índice:", err)
+	}
+	if err = garantirIndice(tabelaPessoas, "idx_"+tabelaPessoas+"_nome", "nome"); err != nil {
+		log.Fatal("Erro ao criar índice:", err)
+	}
+	if err = garantirIndice(tabelaPessoas, "idx_"+tabelaPessoas+"_uuid", "uuid"); err != nil {
+		log.Fatal("Erro ao criar índice:", err)
+	}
+	if err = garantirIndice(tabelaPessoas, "idx_"+tabelaPessoas+"_atualizado_em", "atualizado_em"); err != nil {
+		log.Fatal("Erro ao criar índice:", err)
+	}
+
+	_, err = dbConn.Exec(sqlPessoas(`CREATE TABLE IF NOT EXISTS {auditoria} (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		pessoa_id INT NOT NULL,
+		operacao VARCHAR(10) NOT NULL,
+		valor_anterior JSON NULL,
+		valor_novo JSON NULL,
+		request_id VARCHAR(128) NULL,
+		registrado_em DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		INDEX idx_pessoa (pessoa_id, id)
+	)`))
+	if err != nil {
+		log.Fatal("Erro ao criar a tabela de auditoria:", err)
+	}
+
+	_, err = dbConn.Exec(sqlPessoas(`CREATE TABLE IF NOT EXISTS {versao} (
+		id TINYINT PRIMARY KEY,
+		versao BIGINT NOT NULL
+	)`))
+	if err == nil {
+		_, err = dbConn.Exec(sqlPessoas("INSERT IGNORE INTO {versao} (id, versao) VALUES (1, 0)"))
+	}
+	if err != nil {
+		log.Fatal("Erro ao criar a tabela de versão:", err)
+	}
 }
 
-// listarPessoas responde com a lista de todas as pessoas.
-func listarPessoas(w http.ResponseWriter, r *http.Request) {
-	rows, err := dbConn.Query("SELECT id, nome FROM individuos")
+// colunasEsperadas lista, por tabela, as colunas de que a aplicação depende.
+func colunasEsperadas() map[string][]string {
+	return map[string][]string{
+		tabelaPessoas:   {"id", "uuid", "nome", "criado_em", "atualizado_em"},
+		tabelaAuditoria: {"id", "pessoa_id", "operacao", "valor_anterior", "valor_novo", "request_id", "registrado_em"},
+		tabelaVersao:    {"id", "versao"},
+	}
+}
+
+// indicesEsperados lista, por tabela, os índices criados pela migração.
+func indicesEsperados() map[string][]string {
+	return map[string][]string{
+		tabelaPessoas: {
+			"idx_" + tabelaPessoas + "_criado_em",
+			"idx_" + tabelaPessoas + "_nome",
+			"idx_" + tabelaPessoas + "_uuid",
+			"idx_" + tabelaPessoas + "_atualizado_em",
+		},
+		tabelaAuditoria: {"idx_pessoa"},
+	}
+}
+
+// consultaColunas e consultaIndices leem de information_schema os nomes das
+// colunas e dos índices de uma tabela do banco atual.
+const (
+	consultaColunas = `SELECT COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`
+	consultaIndices = `SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`
+)
+
+// lerNomesEsquema devolve, em minúsculas, os nomes lidos pela consulta para a
+// tabela.
+func lerNomesEsquema(ctx context.Context, consulta, tabela string) (map[string]bool, error) {
+	rows, err := dbConn.QueryContext(ctx, consulta, tabela)
 	if err != nil {
-		http.Error(w, "Erro ao buscar pessoas: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
-	var listaPessoas []Pessoa
+	nomes := make(map[string]bool)
 	for rows.Next() {
-		var p Pessoa
-		if err := rows.Scan(&p.ID, &p.Nome); err != nil {
-			http.Error(w, "Erro ao escanear pessoa: "+err.Error(), http.StatusInternalServerError)
+		var nome string
+		if err := rows.Scan(&nome); err != nil {
+			return nil, err
+		}
+		nomes[strings.ToLower(nome)] = true
+	}
+	return nomes, rows.Err()
+}
+
+// verificarEsquema confere em information_schema que as tabelas e colunas
+// esperadas existem, apontando as que faltam.
+func verificarEsquema() error {
+	var faltando []string
+	for tabela, colunas := range colunasEsperadas() {
+		existentes, err := lerNomesEsquema(context.Background(), consultaColunas, tabela)
+		if err != nil {
+			return err
+		}
+
+		if len(existentes) == 0 {
+			faltando = append(faltando, "tabela "+tabela)
+			continue
+		}
+		for _, coluna := range colunas {
+			if !existentes[coluna] {
+				faltando = append(faltando, tabela+"."+coluna)
+			}
+		}
+	}
+
+	if len(faltando) > 0 {
+		sort.Strings(faltando)
+		return fmt.Errorf("faltam %s", strings.Join(faltando, ", "))
+	}
+	return nil
+}
+
+// ajustarTamanhoNome amplia a coluna nome quando MAX_NAME_LENGTH passou a ser
+// maior que ela. Reduzir a coluna poderia truncar nomes gravados, então nesse
+// caso ela é mantida e apenas a validação passa a usar o limite menor.
+func ajustarTamanhoNome() error {
+	var atual int
+	err := dbConn.QueryRow(`SELECT CHARACTER_MAXIMUM_LENGTH FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'nome'`, tabelaPessoas).Scan(&atual)
+	if err != nil || atual >= tamanhoMaximoNome {
+		if err == nil && atual > tamanhoMaximoNome {
+			log.Printf("Coluna nome tem %d caracteres; MAX_NAME_LENGTH=%d vale só na validação", atual, tamanhoMaximoNome)
+		}
+		return err
+	}
+
+	_, err = dbConn.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY nome VARCHAR(%d) NOT NULL", tabelaPessoas, tamanhoMaximoNome))
+	return err
+}
+
+// verificarCollationNome confere a collation da coluna nome, que é a que vale
+// em nome = ?: com NAME_UNIQUE_CASE_INSENSITIVE=true ela precisa ser _ci, ou
+// "João" e "joão" passariam como nomes diferentes. A collation da conexão
+// (DB_COLLATION) não basta, já que a coluna pode ter sido criada com outra.
+func verificarCollationNome() error {
+	if !nomesUnicosSemCaixa {
+		return nil
+	}
+	var collation sql.NullString
+	err := dbConn.QueryRow(`SELECT COLLATION_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'nome'`, tabelaPessoas).Scan(&collation)
+	if err != nil {
+		return fmt.Errorf("erro ao ler a collation da coluna nome: %w", err)
+	}
+	if !strings.HasSuffix(collation.String, "_ci") {
+		return fmt.Errorf("NAME_UNIQUE_CASE_INSENSITIVE=true exige uma collation _ci na coluna %s.nome, mas ela usa %q", tabelaPessoas, collation.String)
+	}
+	return nil
+}
+
+// garantirIndice cria o índice sobre as colunas informadas quando ele ainda não
+// existe, já que o MySQL não suporta CREATE INDEX IF NOT EXISTS.
+func garantirIndice(tabela, indice, colunas string) error {
+	var existe bool
+	err := dbConn.QueryRow(`SELECT COUNT(*) > 0 FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?`, tabela, indice).Scan(&existe)
+	if err != nil || existe {
+		return err
+	}
+
+	_, err = dbConn.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (%s)", indice, tabela, colunas))
+	return err
+}
+
+// preencherUUIDs gera, em lotes, o uuid das pessoas gravadas antes da coluna
+// existir. Os valores vêm de uuid.NewString, aleatórios como os das pessoas
+// novas, e não da função UUID() do MySQL, que segue a hora e o host.
+func preencherUUIDs() error {
+	for {
+		rows, err := dbConn.Query(sqlPessoas("SELECT id FROM {tabela} WHERE uuid IS NULL LIMIT 1000"))
+		if err != nil {
+			return err
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for _, id := range ids {
+			if _, err := dbConn.Exec(sqlPessoas("UPDATE {tabela} SET uuid = ? WHERE id = ? AND uuid IS NULL"), uuid.NewString(), id); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// garantirColuna adiciona a coluna à tabela quando ela ainda não existe, para
+// que bancos criados por versões anteriores recebam as colunas novas.
+func garantirColuna(tabela, coluna, definicao string) error {
+	var existe bool
+	err := dbConn.QueryRow(`SELECT COUNT(*) > 0 FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`, tabela, coluna).Scan(&existe)
+	if err != nil || existe {
+		return err
+	}
+
+	_, err = dbConn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tabela, coluna, definicao))
+	return err
+}
+
+// responderJSON escreve v como JSON na resposta, com o status informado. A saída
+// é compacta por padrão e indentada quando a requisição traz ?pretty=true, útil
+// para depuração. Quando o cliente pede application/vnd.api+json, pessoas e
+// erros são convertidos para o formato JSON:API. A codificação é feita num
+// buffer reaproveitado de buffersJSON, o que também permite informar
+// Content-Length. As chaves seguem JSON_KEY_CASE.
+func responderJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	contentType := "application/json"
+	if aceitaJSONAPI(r) {
+		if documento, ok := documentoJSONAPI(status, v); ok {
+			v, contentType = documento, mediaTypeJSONAPI
+		}
+	}
+
+	buf := buffersJSON.Get().(*bytes.Buffer)
+	defer devolverBufferJSON(buf)
+
+	encoder := json.NewEncoder(buf)
+	pretty := r.URL.Query().Get("pretty") == "true"
+	if pretty && !chavesCamelCase {
+		encoder.SetIndent("", "  ")
+	}
+	err := encoder.Encode(v)
+	if err == nil && chavesCamelCase {
+		err = recodificarCamelCase(buf, pretty)
+	}
+	if err != nil {
+		log.Printf("[%s] Erro ao codificar resposta: %v", requestID(r.Context()), err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// buffersJSON guarda os buffers usados por responderJSON, poupando uma alocação
+// por resposta nas listagens.
+var buffersJSON = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxBufferJSON é o maior buffer devolvido ao pool; os maiores, de listagens
+// excepcionais, são descartados para não ficarem retidos na memória.
+const maxBufferJSON = 64 << 10
+
+func devolverBufferJSON(buf *bytes.Buffer) {
+	if buf.Cap() > maxBufferJSON {
+		return
+	}
+	buf.Reset()
+	buffersJSON.Put(buf)
+}
+
+// RespostaErro é o corpo devolvido em respostas de erro. RequestID aparece nas
+// falhas internas, para que o cliente possa citá-lo ao reportar o problema; a
+// linha de log do erro começa pelo mesmo id entre colchetes.
+type RespostaErro struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// exporDetalhesErro, configurável por EXPOSE_ERROR_DETAILS, inclui o erro do
+// banco nas respostas 500. Fica desligado por padrão porque o texto revela
+// nomes de tabelas, colunas e códigos SQL; em desenvolvimento ele poupa uma ida
+// aos logs.
+var exporDetalhesErro = lerEnvBool("EXPOSE_ERROR_DETAILS", false)
+
+// responderErro escreve uma resposta de erro em JSON com o status informado.
+func responderErro(w http.ResponseWriter, r *http.Request, status int, mensagem string) {
+	responderJSON(w, r, status, RespostaErro{Error: mensagem})
+}
+
+// responderErroBanco responde a uma falha do repositório: 503 com Retry-After
+// quando o banco está sobrecarregado e 500 nos demais casos, com o texto da
+// chave do catálogo e o id da requisição. O erro completo vai para o log e só é
+// anexado à mensagem com exporDetalhesErro.
+func responderErroBanco(w http.ResponseWriter, r *http.Request, chave string, err error) {
+	if errors.Is(err, ErrBancoSobrecarregado) {
+		w.Header().Set("Retry-After", "1")
+		responderErro(w, r, http.StatusServiceUnavailable, mensagem(r, "servico_sobrecarregado"))
+		return
+	}
+	id := requestID(r.Context())
+	log.Printf("[%s] %s: %v", id, chave, err)
+
+	texto := mensagem(r, chave)
+	if exporDetalhesErro {
+		texto += ": " + err.Error()
+	}
+	responderJSON(w, r, http.StatusInternalServerError, RespostaErro{Error: texto, RequestID: id})
+}
+
+// lerParamInt lê o parâmetro de query nome como inteiro. O segundo retorno
+// indica se o parâmetro foi informado.
+func lerParamInt(params url.Values, nome string) (int, bool, error) {
+	valor := params.Get(nome)
+	if valor == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(valor)
+	if err != nil {
+		return 0, false, novoErro("param_inteiro", nome)
+	}
+	return n, true, nil
+}
+
+// lerParamData lê o parâmetro de query nome como data RFC 3339 ou AAAA-MM-DD
+// (meia-noite em UTC), devolvendo nil quando ele não foi informado.
+func lerParamData(params url.Values, nome string) (*time.Time, error) {
+	valor := params.Get(nome)
+	if valor == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, valor)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, valor); err != nil {
+			return nil, novoErro("param_data", nome)
+		}
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+// incluir informa se o campo derivado foi pedido em ?include=, que aceita uma
+// lista separada por vírgulas.
+func incluir(r *http.Request, campo string) bool {
+	for _, item := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(item) == campo {
+			return true
+		}
+	}
+	return false
+}
+
+// preencherDerivados calcula os campos derivados pedidos pela requisição e
+// aplica o formato de data de ?time_format. Eles nunca são gravados no banco.
+func preencherDerivados(r *http.Request, p *Pessoa) {
+	if incluir(r, "nome_length") {
+		p.NomeLength = utf8.RuneCountInString(p.Nome)
+	}
+	if incluir(r, "display_name") {
+		p.DisplayName = p.NomeExibicao()
+	}
+	formato := r.URL.Query().Get("time_format")
+	p.CriadoEm = formatarInstante(formato, p.CriadoEm)
+	p.AtualizadoEm = formatarInstante(formato, p.AtualizadoEm)
+}
+
+// preferenciaMinima informa se o cliente pediu Prefer: return=minimal.
+func preferenciaMinima(r *http.Request) bool {
+	for _, valor := range r.Header.Values("Prefer") {
+		for _, preferencia := range strings.Split(valor, ",") {
+			if strings.EqualFold(strings.TrimSpace(preferencia), "return=minimal") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// responderEscrita responde a uma criação ou atualização bem-sucedida com a
+// pessoa gravada ou, com Prefer: return=minimal, com 204 e apenas o Location.
+func responderEscrita(w http.ResponseWriter, r *http.Request, p Pessoa) {
+	if preferenciaMinima(r) {
+		w.Header().Del("Content-Type")
+		w.Header().Set("Location", "/pessoas/"+strconv.Itoa(p.ID))
+		w.Header().Set("Preference-Applied", "return=minimal")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	preencherDerivados(r, &p)
+	responderJSON(w, r, http.StatusOK, p)
+}
+
+// parseID converte o id da URL, aceitando apenas inteiros não negativos na forma
+// canônica: sem sinal, espaços ou zeros à esquerda. Assim /pessoas/007 e
+// /pessoas/+1 são recusados em vez de normalizados silenciosamente pelo MySQL.
+func parseID(valor string) (int, error) {
+	if valor == "" || (len(valor) > 1 && valor[0] == '0') {
+		return 0, novoErro("id_invalido")
+	}
+	for _, c := range valor {
+		if c < '0' || c > '9' {
+			return 0, novoErro("id_invalido")
+		}
+	}
+
+	id, err := strconv.Atoi(valor)
+	if err != nil {
+		return 0, novoErro("id_invalido")
+	}
+	return id, nil
+}
+
+// tamanhoPaginaPadrao é o tamanho de página usado quando a paginação é pedida
+// sem limit nem per_page.
+const tamanhoPaginaPadrao = 20
+
+// lerPaginacao interpreta os dois estilos de paginação aceitos por listarPessoas:
+// offset/limit e page/per_page (page começa em 1). Se os dois estilos vierem
+// juntos, offset/limit tem precedência, mas ambos precisam descrever a mesma
+// página; se divergirem a requisição é recusada. O terceiro retorno indica se
+// algum parâmetro de paginação foi informado.
+func lerPaginacao(params url.Values) (deslocamento, limite int, paginado bool, err error) {
+	offset, temOffset, err := lerParamInt(params, "offset")
+	if err != nil {
+		return 0, 0, false, err
+	}
+	limit, temLimit, err := lerParamInt(params, "limit")
+	if err != nil {
+		return 0, 0, false, err
+	}
+	pagina, temPagina, err := lerParamInt(params, "page")
+	if err != nil {
+		return 0, 0, false, err
+	}
+	porPagina, temPorPagina, err := lerParamInt(params, "per_page")
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	estiloOffset := temOffset || temLimit
+	estiloPagina := temPagina || temPorPagina
+	if !estiloOffset && !estiloPagina {
+		return 0, 0, false, nil
+	}
+
+	if !temLimit {
+		limit = tamanhoPaginaPadrao
+	}
+	if !temPorPagina {
+		porPagina = tamanhoPaginaPadrao
+	}
+	if !temPagina {
+		pagina = 1
+	}
+
+	if offset < 0 || limit < 1 || pagina < 1 || porPagina < 1 {
+		return 0, 0, false, novoErro("paginacao_invalida")
+	}
+	if limit > limiteResultados || porPagina > limiteResultados {
+		return 0, 0, false, novoErro("paginacao_maxima", limiteResultados)
+	}
+
+	if estiloOffset {
+		if estiloPagina && ((pagina-1)*porPagina != offset || porPagina != limit) {
+			return 0, 0, false, novoErro("paginacao_conflito")
+		}
+		return offset, limit, true, nil
+	}
+	return (pagina - 1) * porPagina, porPagina, true, nil
+}
+
+// lerFiltroPessoas lê os filtros min_id, max_id, ids (lista separada por
+// vírgulas, com até maxIDsPorConsulta ids), nome_exato, criado_apos e
+// criado_antes, comuns à listagem e à contagem. nome_exato passa pela mesma
+// normalização da gravação, para casar com o nome como foi salvo; as datas
+// seguem lerParamData e formam o intervalo [criado_apos, criado_antes).
+func lerFiltroPessoas(params url.Values) (FiltroPessoas, error) {
+	filtro := FiltroPessoas{NomeExato: normalizarNome(params.Get("nome_exato"))}
+
+	if valor := params.Get("ids"); valor != "" {
+		itens := strings.Split(valor, ",")
+		if len(itens) > maxIDsPorConsulta {
+			return filtro, novoErro("ids_demais", maxIDsPorConsulta)
+		}
+		for _, item := range itens {
+			id, err := parseID(strings.TrimSpace(item))
+			if err != nil {
+				return filtro, err
+			}
+			filtro.IDs = append(filtro.IDs, id)
+		}
+	}
+
+	minID, temMin, err := lerParamInt(params, "min_id")
+	if err != nil {
+		return filtro, err
+	}
+	maxID, temMax, err := lerParamInt(params, "max_id")
+	if err != nil {
+		return filtro, err
+	}
+	if temMin && temMax && minID > maxID {
+		return filtro, novoErro("min_max_id")
+	}
+	if temMin {
+		filtro.MinID = &minID
+	}
+	if temMax {
+		filtro.MaxID = &maxID
+	}
+	if filtro.CriadoApos, err = lerParamData(params, "criado_apos"); err != nil {
+		return filtro, err
+	}
+	if filtro.CriadoAntes, err = lerParamData(params, "criado_antes"); err != nil {
+		return filtro, err
+	}
+	return filtro, nil
+}
+
+// contarPessoas atende HEAD /pessoas: responde sem corpo, apenas com o total de
+// pessoas que casam com os filtros no cabeçalho X-Total-Count, para clientes que
+// só precisam calcular o número de páginas.
+func contarPessoas(w http.ResponseWriter, r *http.Request) {
+	filtro, err := lerFiltroPessoas(r.URL.Query())
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+
+	total, err := repositorio.Count(r.Context(), filtro)
+	if err != nil {
+		responderErroBanco(w, r, "erro_contar_pessoas", err)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.WriteHeader(http.StatusOK)
+}
+
+// cabecalhosTotal, configurável por PAGINATION_TOTAL_HEADERS, faz as listagens
+// paginadas enviarem X-Total-Count e Content-Range, esperados por frameworks
+// de administração como o react-admin. Custa um COUNT(*) por página.
+var cabecalhosTotal = lerEnvBool("PAGINATION_TOTAL_HEADERS", true)
+
+// definirCabecalhosTotal escreve X-Total-Count e Content-Range ("pessoas
+// 0-19/100", ou "pessoas */0" numa página vazia) para uma página de quantidade
+// itens a partir de deslocamento.
+func definirCabecalhosTotal(w http.ResponseWriter, deslocamento, quantidade, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if quantidade == 0 {
+		w.Header().Set("Content-Range", fmt.Sprintf("pessoas */%d", total))
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("pessoas %d-%d/%d", deslocamento, deslocamento+quantidade-1, total))
+}
+
+// listarPessoas responde com a lista de todas as pessoas. A ordenação padrão é
+// por id crescente; sort (id, nome, criado_em ou atualizado_em) e order (asc ou
+// desc) permitem escolher outra, sempre desempatando pelo id. Sem order, a
+// direção segue ordemPadraoDescendente (datas em ordem decrescente). Em colunas
+// anuláveis, nulls=first|last põe os nulos no início ou no fim; o padrão é last
+// nas duas direções, de modo que pessoas nunca atualizadas não encabeçam a lista
+// de sort=atualizado_em. Com ids= e preserve_order=true (sem sort), a lista segue
+// a ordem dos ids pedidos, via FIELD() do MySQL. Os filtros seguem
+// lerFiltroPessoas; nome_exato usa o índice de nome e pode casar várias pessoas.
+// A paginação segue lerPaginacao; sem ela, o resultado é limitado a
+// limiteResultados. Com parâmetros válidos, a resposta traz o ETag de
+// etagColecao e, com If-None-Match correspondente, é 304 sem corpo.
+func listarPessoas(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	filtro, err := lerFiltroPessoas(params)
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+
+	filtro.Ordenacao = params.Get("sort")
+	if filtro.Ordenacao != "" && !colunasOrdenaveis[filtro.Ordenacao] {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "sort_invalido"))
+		return
+	}
+
+	switch strings.ToLower(params.Get("order")) {
+	case "":
+		filtro.Descendente = ordemPadraoDescendente[filtro.Ordenacao]
+	case "asc":
+	case "desc":
+		filtro.Descendente = true
+	default:
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "order_invalido"))
+		return
+	}
+
+	if params.Get("preserve_order") == "true" {
+		if len(filtro.IDs) == 0 || filtro.Ordenacao != "" {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "preserve_order_invalido"))
 			return
 		}
-		listaPessoas = append(listaPessoas, p)
+		filtro.OrdemIDs = true
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(listaPessoas)
+	switch strings.ToLower(params.Get("nulls")) {
+	case "", "last":
+	case "first":
+		filtro.NulosPrimeiro = true
+	default:
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "nulls_invalido"))
+		return
+	}
+
+	deslocamento, limite, paginado, err := lerPaginacao(params)
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+	if paginado {
+		filtro.Limite, filtro.Deslocamento = limite, deslocamento
+	} else {
+		// Busca uma linha além do limite apenas para saber se houve truncamento.
+		filtro.Limite = limiteResultados + 1
+	}
+
+	// O estado é lido antes da lista: uma escrita entre as duas consultas deixa
+	// o ETag mais antigo que o corpo, e nunca o contrário.
+	var etag string
+	if etagListas {
+		estado, err := repositorio.CollectionState(r.Context())
+		if err != nil {
+			responderErroBanco(w, r, "erro_buscar_pessoas", err)
+			return
+		}
+		etag = etagColecao(r, estado)
+		if etagCorresponde(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	listaPessoas, err := repositorio.List(r.Context(), filtro)
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_pessoas", err)
+		return
+	}
+
+	if !paginado && len(listaPessoas) > limiteResultados {
+		listaPessoas = listaPessoas[:limiteResultados]
+		w.Header().Set("X-Result-Truncated", "true")
+	}
+
+	if paginado && cabecalhosTotal {
+		total, err := repositorio.Count(r.Context(), filtro)
+		if err != nil {
+			responderErroBanco(w, r, "erro_contar_pessoas", err)
+			return
+		}
+		definirCabecalhosTotal(w, deslocamento, len(listaPessoas), total)
+	}
+
+	for i := range listaPessoas {
+		preencherDerivados(r, &listaPessoas[i])
+	}
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if paginado && aceitaJSONAPI(r) {
+		responderJSON(w, r, http.StatusOK, DocumentoJSONAPI{
+			Data:  recursosPessoas(listaPessoas),
+			Links: linksPaginacao(r, deslocamento, limite, len(listaPessoas)),
+		})
+		return
+	}
+	responderJSON(w, r, http.StatusOK, listaPessoas)
 }
 
-// obterPessoa responde com os detalhes de uma pessoa pelo seu ID.
+// listarPessoasRecentes responde com as pessoas cadastradas mais recentemente,
+// da mais nova para a mais antiga. O parâmetro limit vai de 1 a 100 (padrão 10).
+func listarPessoasRecentes(w http.ResponseWriter, r *http.Request) {
+	limite, informado, err := lerParamInt(r.URL.Query(), "limit")
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+	if !informado {
+		limite = 10
+	}
+	if limite < 1 || limite > 100 {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "limit_faixa", 1, 100))
+		return
+	}
+
+	listaPessoas, err := repositorio.List(r.Context(), FiltroPessoas{
+		Ordenacao:   "criado_em",
+		Descendente: true,
+		Limite:      limite,
+	})
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_pessoas", err)
+		return
+	}
+
+	for i := range listaPessoas {
+		preencherDerivados(r, &listaPessoas[i])
+	}
+	responderJSON(w, r, http.StatusOK, listaPessoas)
+}
+
+// Sugestao é um item do autocompletar de nomes.
+type Sugestao struct {
+	ID   int    `json:"id"`
+	Nome string `json:"nome"`
+}
+
+// sugerirPessoas responde com os nomes que começam com prefix, para
+// autocompletar. O prefixo precisa de ao menos 2 caracteres e limit vai de 1 a
+// 20 (padrão 5), evitando varreduras grandes.
+func sugerirPessoas(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	prefixo := strings.TrimSpace(params.Get("prefix"))
+	if utf8.RuneCountInString(prefixo) < 2 {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "prefixo_curto", 2))
+		return
+	}
+
+	limite, informado, err := lerParamInt(params, "limit")
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+	if !informado {
+		limite = 5
+	}
+	if limite < 1 || limite > 20 {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "limit_faixa", 1, 20))
+		return
+	}
+
+	sugestoes, err := repositorio.Suggest(r.Context(), prefixo, limite)
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_sugestoes", err)
+		return
+	}
+	responderJSON(w, r, http.StatusOK, sugestoes)
+}
+
+// Inicial é um item do índice alfabético de nomes.
+type Inicial struct {
+	Letter string `json:"letter"`
+	Count  int    `json:"count"`
+}
+
+// listarIniciais responde com as letras iniciais que têm cadastros e quantos
+// cada uma tem, para a navegação de A a Z.
+func listarIniciais(w http.ResponseWriter, r *http.Request) {
+	iniciais, err := repositorio.Initials(r.Context())
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_pessoas", err)
+		return
+	}
+	responderJSON(w, r, http.StatusOK, iniciais)
+}
+
+// Duplicado é um grupo de pessoas com o mesmo nome.
+type Duplicado struct {
+	Nome string `json:"nome"`
+	IDs  []int  `json:"ids"`
+}
+
+// listarDuplicados responde com os grupos de pessoas que têm o mesmo nome, na
+// comparação de NAME_UNIQUE_CASE_INSENSITIVE, para limpar os dados antes de
+// exigir nomes únicos.
+func listarDuplicados(w http.ResponseWriter, r *http.Request) {
+	duplicados, err := repositorio.Duplicates(r.Context())
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_pessoas", err)
+		return
+	}
+	responderJSON(w, r, http.StatusOK, duplicados)
+}
+
+// obterPessoa responde com os detalhes de uma pessoa pelo seu ID. Com o cache
+// de leitura habilitado, uma falha do banco é contornada servindo a última
+// versão lida, sinalizada por X-Served-From-Cache e Warning.
 func obterPessoa(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id := params["id"]
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
+	relacoes, err := lerExpansoes(r)
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
+		return
+	}
 
-	var p Pessoa
-	err := dbConn.QueryRow("SELECT id, nome FROM individuos WHERE id = ?", id).Scan(&p.ID, &p.Nome)
+	p, err := repositorio.Get(r.Context(), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Pessoa não encontrada", http.StatusNotFound)
-		} else {
-			http.Error(w, "Erro ao buscar pessoa: "+err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, ErrPessoaNaoEncontrada) {
+			cachePessoas.remover(id)
+			responderErro(w, r, http.StatusNotFound, mensagem(r, "pessoa_nao_encontrada"))
+			return
 		}
-		return
+
+		guardada, ok := cachePessoas.obter(id)
+		if !ok {
+			responderErroBanco(w, r, "erro_buscar_pessoa", err)
+			return
+		}
+		log.Printf("[%s] Servindo pessoa %d do cache após erro no banco: %v", requestID(r.Context()), id, err)
+		w.Header().Set("X-Served-From-Cache", "true")
+		w.Header().Set("Warning", `111 - "Revalidation Failed"`)
+		p = guardada
+	} else {
+		cachePessoas.guardar(p)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(p)
+	if err := expandir(r.Context(), &p, relacoes); err != nil {
+		responderErroBanco(w, r, "erro_buscar_pessoa", err)
+		return
+	}
+	preencherDerivados(r, &p)
+	responderJSON(w, r, http.StatusOK, p)
 }
 
-// adicionarPessoa adiciona uma nova pessoa ao banco de dados.
-func adicionarPessoa(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// obterPessoaPorUUID responde com a pessoa de GET /pessoas/by-uuid/{uuid}, para
+// clientes que guardam apenas o identificador público.
+func obterPessoaPorUUID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "uuid_invalido"))
+		return
+	}
 
-	if r.Header.Get("Content-Type") != "application/json" {
-		http.Error(w, "O Content-Type deve ser application/json", http.StatusUnsupportedMediaType)
+	p, err := repositorio.GetByUUID(r.Context(), id.String())
+	if errors.Is(err, ErrPessoaNaoEncontrada) {
+		responderErro(w, r, http.StatusNotFound, mensagem(r, "pessoa_nao_encontrada"))
 		return
 	}
+	if err != nil {
+		responderErroBanco(w, r, "erro_buscar_pessoa", err)
+		return
+	}
+	preencherDerivados(r, &p)
+	responderJSON(w, r, http.StatusOK, p)
+}
 
-	var novaPessoa Pessoa
-	err := json.NewDecoder(r.Body).Decode(&novaPessoa)
+// ExistenciaPessoa é a resposta de existePessoa.
+type ExistenciaPessoa struct {
+	Exists bool `json:"exists"`
+}
+
+// existePessoa responde 200 com {"exists": true|false}, para clientes que só
+// precisam saber se o id existe e não querem ler o registro nem usar HEAD.
+func existePessoa(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Erro ao decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
 		return
 	}
 
-	resultado, err := dbConn.Exec("INSERT INTO individuos (nome) VALUES (?)", novaPessoa.Nome)
+	existe, err := repositorio.Exists(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Erro ao inserir pessoa: "+err.Error(), http.StatusInternalServerError)
+		responderErroBanco(w, r, "erro_buscar_pessoa", err)
 		return
 	}
+	responderJSON(w, r, http.StatusOK, ExistenciaPessoa{Exists: existe})
+}
+
+// redirecionamentoLocal aceita apenas caminhos do próprio site, evitando que
+// redirect_to seja usado como redirecionamento aberto para outro domínio.
+func redirecionamentoLocal(destino string) bool {
+	return strings.HasPrefix(destino, "/") && !strings.HasPrefix(destino, "//") && !strings.HasPrefix(destino, "/\\")
+}
+
+// lerPessoaCriacao interpreta o corpo de uma criação, em JSON ou formulário,
+// e normaliza o nome. Em caso de erro já responde e devolve false.
+func lerPessoaCriacao(w http.ResponseWriter, r *http.Request) (Pessoa, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var p Pessoa
+	switch mediaType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			if !corpoExcedido(w, r, err) {
+				responderErro(w, r, http.StatusBadRequest, mensagem(r, "json_invalido", err))
+			}
+			return p, false
+		}
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		p.Nome = r.FormValue("nome")
+	default:
+		responderErro(w, r, http.StatusUnsupportedMediaType, mensagem(r, "content_type_criacao"))
+		return p, false
+	}
+
+	p.Nome = normalizarNome(p.Nome)
+	return p, true
+}
+
+// ResultadoValidacao é a resposta de validarNome.
+type ResultadoValidacao struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
 
-	id, err := resultado.LastInsertId()
+// validarNome aplica ao corpo as mesmas regras de adicionarPessoa sem gravar
+// nada, para que o frontend valide antes de enviar. Como na criação, o nome só
+// precisa ser único quando a requisição traz If-None-Match: *.
+func validarNome(w http.ResponseWriter, r *http.Request) {
+	p, ok := lerPessoaCriacao(w, r)
+	if !ok {
+		return
+	}
+
+	if err := validarPessoa(p); err != nil {
+		responderJSON(w, r, http.StatusOK, ResultadoValidacao{Errors: []string{mensagemErro(r, err)}})
+		return
+	}
+
+	switch r.Header.Get("If-None-Match") {
+	case "":
+	case "*":
+		existe, err := repositorio.NameExists(r.Context(), p.Nome)
+		if err != nil {
+			responderErroBanco(w, r, "erro_buscar_pessoa", err)
+			return
+		}
+		if existe {
+			responderJSON(w, r, http.StatusOK, ResultadoValidacao{Errors: []string{mensagem(r, "pessoa_duplicada")}})
+			return
+		}
+	default:
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "if_none_match_invalido"))
+		return
+	}
+
+	responderJSON(w, r, http.StatusOK, ResultadoValidacao{Valid: true})
+}
+
+// adicionarPessoa adiciona uma nova pessoa ao banco de dados. Aceita tanto JSON
+// quanto formulários HTML (campo "nome"). Com redirect_to (no formulário ou na
+// query) responde 303 See Other para esse caminho, no padrão Post/Redirect/Get.
+// Com If-None-Match: * só cria se não houver pessoa com o mesmo nome,
+// respondendo 412 caso contrário.
+func adicionarPessoa(w http.ResponseWriter, r *http.Request) {
+	novaPessoa, ok := lerPessoaCriacao(w, r)
+	if !ok {
+		return
+	}
+	if err := validarPessoa(novaPessoa); err != nil {
+		responderErro(w, r, statusErroValidacao(err), mensagemErro(r, err))
+		return
+	}
+	destino := r.FormValue("redirect_to")
+	if destino != "" && !redirecionamentoLocal(destino) {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "redirect_invalido"))
+		return
+	}
+
+	if !guardaNomes.registrar(novaPessoa.Nome) {
+		responderErro(w, r, http.StatusConflict, mensagem(r, "submissao_duplicada"))
+		return
+	}
+
+	var err error
+	switch r.Header.Get("If-None-Match") {
+	case "":
+		novaPessoa, err = repositorio.Create(r.Context(), novaPessoa)
+	case "*":
+		novaPessoa, err = repositorio.CreateIfAbsent(r.Context(), novaPessoa)
+	default:
+		guardaNomes.liberar(novaPessoa.Nome)
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "if_none_match_invalido"))
+		return
+	}
 	if err != nil {
-		http.Error(w, "Erro ao obter ID da nova pessoa: "+err.Error(), http.StatusInternalServerError)
+		guardaNomes.liberar(novaPessoa.Nome)
+		if errors.Is(err, ErrPessoaDuplicada) {
+			responderErro(w, r, http.StatusPreconditionFailed, mensagem(r, "pessoa_duplicada"))
+		} else {
+			responderErroBanco(w, r, "erro_inserir_pessoa", err)
+		}
+		return
+	}
+	publicarEvento(eventoCriado, novaPessoa)
+
+	if destino != "" {
+		http.Redirect(w, r, destino, http.StatusSeeOther)
 		return
 	}
 
-	novaPessoa.ID = int(id)
-	json.NewEncoder(w).Encode(novaPessoa)
+	responderEscrita(w, r, novaPessoa)
 }
 
-// removerPessoa deleta uma pessoa pelo seu ID.
+// removerPessoa deleta uma pessoa pelo seu ID e responde 204, mesmo que ela
+// não existisse. Com ?return=representation responde 200 com o registro
+// removido (ou 404 se ele não existia), poupando o cliente de um GET antes do
+// DELETE.
 func removerPessoa(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id := params["id"]
-
-	_, err := dbConn.Exec("DELETE FROM individuos WHERE id = ?", id)
+	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Erro ao deletar pessoa: "+err.Error(), http.StatusInternalServerError)
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	removida, err := repositorio.Delete(r.Context(), id)
+	encontrada := !errors.Is(err, ErrPessoaNaoEncontrada)
+	if err != nil && encontrada {
+		responderErroBanco(w, r, "erro_deletar_pessoa", err)
+		return
+	}
+	cachePessoas.remover(id)
+	if encontrada {
+		publicarEvento(eventoRemovido, removida)
+	}
+
+	if r.URL.Query().Get("return") != "representation" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !encontrada {
+		responderErro(w, r, http.StatusNotFound, mensagem(r, "pessoa_nao_encontrada"))
+		return
+	}
+	preencherDerivados(r, &removida)
+	responderJSON(w, r, http.StatusOK, removida)
 }
 
 // modificarPessoa atualiza o nome de uma pessoa pelo seu ID.
@@ -136,66 +1273,175 @@ func modificarPessoa(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Header.Get("Content-Type") != "application/json" {
-		http.Error(w, "O Content-Type deve ser application/json", http.StatusUnsupportedMediaType)
+		responderErro(w, r, http.StatusUnsupportedMediaType, mensagem(r, "content_type_json"))
 		return
 	}
 
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
+	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		responderErro(w, r, http.StatusBadRequest, mensagemErro(r, err))
 		return
 	}
 
 	var pessoaAtualizada Pessoa
 	err = json.NewDecoder(r.Body).Decode(&pessoaAtualizada)
 	if err != nil {
-		http.Error(w, "Erro ao decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		if !corpoExcedido(w, r, err) {
+			responderErro(w, r, http.StatusBadRequest, mensagem(r, "json_invalido", err))
+		}
 		return
 	}
 
-	resultado, err := dbConn.Exec("UPDATE individuos SET nome = ? WHERE id = ?", pessoaAtualizada.Nome, id)
-	if err != nil {
-		http.Error(w, "Erro ao atualizar pessoa: "+err.Error(), http.StatusInternalServerError)
+	if pessoaAtualizada.ID != 0 && pessoaAtualizada.ID != id {
+		responderErro(w, r, http.StatusBadRequest, mensagem(r, "id_divergente"))
 		return
 	}
 
-	linhasAfetadas, err := resultado.RowsAffected()
-	if err != nil {
-		http.Error(w, "Erro ao verificar linhas afetadas: "+err.Error(), http.StatusInternalServerError)
+	pessoaAtualizada.Nome = normalizarNome(pessoaAtualizada.Nome)
+	if err := validarPessoa(pessoaAtualizada); err != nil {
+		responderErro(w, r, statusErroValidacao(err), mensagemErro(r, err))
 		return
 	}
 
-	if linhasAfetadas == 0 {
-		http.Error(w, "Pessoa não encontrada", http.StatusNotFound)
+	pessoaAtualizada.ID = id
+	pessoaAtualizada, err = repositorio.Update(r.Context(), pessoaAtualizada)
+	if err != nil {
+		if errors.Is(err, ErrPessoaNaoEncontrada) {
+			responderErro(w, r, http.StatusNotFound, mensagem(r, "pessoa_nao_encontrada"))
+		} else {
+			responderErroBanco(w, r, "erro_atualizar_pessoa", err)
+		}
 		return
 	}
+	cachePessoas.guardar(pessoaAtualizada)
+	publicarEvento(eventoAtualizado, pessoaAtualizada)
 
-	pessoaAtualizada.ID = id
-	json.NewEncoder(w).Encode(pessoaAtualizada)
+	responderEscrita(w, r, pessoaAtualizada)
 }
 
-// bemVindo responde com uma mensagem de boas-vindas.
+// InfoServico descreve o serviço para clientes automatizados que acessam a raiz.
+type InfoServico struct {
+	Service        string `json:"service"`
+	Version        string `json:"version"`
+	Docs           string `json:"docs"`
+	NameUniqueness string `json:"name_uniqueness"`
+}
+
+// bemVindo responde com uma mensagem de boas-vindas para navegadores e com as
+// informações do serviço em JSON para os demais clientes.
 func bemVindo(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, "Bem-vindo ao nosso serviço!")
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		fmt.Fprint(w, mensagem(r, "boas_vindas"))
+		return
+	}
+
+	responderJSON(w, r, http.StatusOK, InfoServico{
+		Service:        "pessoas-api",
+		Version:        versao,
+		Docs:           "/docs",
+		NameUniqueness: modoUnicidadeNome(),
+	})
 }
 
 func main() {
-	configurarDB()
-	defer dbConn.Close()
+	if err := configurarCaixaNome(); err != nil {
+		log.Fatal(err)
+	}
+	if err := configurarBloqueioNomes(); err != nil {
+		log.Fatal("Erro ao carregar a lista de bloqueio de nomes: ", err)
+	}
 
 	r := mux.NewRouter()
 
-	r.HandleFunc("/", bemVindo)
+	// DISABLE_ROOT_ROUTE=true deixa / sem rota (404), para implantações só de
+	// API em que monitores de disponibilidade não devem confundir a página de
+	// boas-vindas com um serviço saudável; /health é a rota para isso.
+	if !lerEnvBool("DISABLE_ROOT_ROUTE", false) {
+		r.HandleFunc("/", bemVindo)
+	}
+	r.HandleFunc("/healthz", vivacidade).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", prontidao).Methods(http.MethodGet)
+	r.HandleFunc("/health", saude).Methods(http.MethodGet)
 	r.HandleFunc("/pessoas", listarPessoas).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas", contarPessoas).Methods(http.MethodHead)
 	r.HandleFunc("/pessoas", adicionarPessoa).Methods(http.MethodPost)
+	r.HandleFunc("/pessoas/import", limitarLote(importarPessoas)).Methods(http.MethodPost)
+	r.HandleFunc("/pessoas/validate", validarNome).Methods(http.MethodPost)
+	r.HandleFunc("/pessoas/recent", listarPessoasRecentes).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/events", transmitirEventos).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/suggest", sugerirPessoas).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/initials", listarIniciais).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/duplicates", listarDuplicados).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/bulk", limitarLote(atualizarPessoasEmLote)).Methods(http.MethodPut)
+	r.HandleFunc("/pessoas/batch", limitarLote(executarEnvelope)).Methods(http.MethodPost)
+	r.HandleFunc("/pessoas/export", exportarPessoasCSV).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/stream", transmitirPessoasNDJSON).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/by-uuid/{uuid}", obterPessoaPorUUID).Methods(http.MethodGet)
 	r.HandleFunc("/pessoas/{id}", obterPessoa).Methods(http.MethodGet)
 	r.HandleFunc("/pessoas/{id}", removerPessoa).Methods(http.MethodDelete)
 	r.HandleFunc("/pessoas/{id}", modificarPessoa).Methods(http.MethodPut)
+	r.HandleFunc("/pessoas/{id}/history", historicoPessoa).Methods(http.MethodGet)
+	r.HandleFunc("/pessoas/{id}/exists", existePessoa).Methods(http.MethodGet)
+
+	r.Use(rotaMiddleware, limiteTaxaMiddleware(), limiteSimultaneasMiddleware(), formatoInstanteMiddleware, somenteLeituraMiddleware, timeoutMiddleware())
+
+	if lerEnvBool("ENABLE_DEBUG_DB", false) {
+		r.HandleFunc("/debug/db", estatisticasDB).Methods(http.MethodGet)
+	}
+	tokenAdmin := lerEnv("ADMIN_TOKEN", "")
+	if tokenAdmin != "" {
+		r.HandleFunc("/admin/schema-check", exigirAdmin(tokenAdmin, verificarEsquemaAdmin)).Methods(http.MethodGet)
+	}
+	if lerEnvBool("ENABLE_ADMIN_SHUTDOWN", false) {
+		r.HandleFunc("/admin/shutdown", exigirAdmin(tokenAdmin, encerrarServidor)).Methods(http.MethodPost)
+	}
+	// O modo somente leitura só pode ser alterado com ADMIN_TOKEN definido.
+	if tokenAdmin != "" {
+		r.HandleFunc("/admin/readonly", exigirAdmin(tokenAdmin, consultarModoLeitura)).Methods(http.MethodGet)
+		r.HandleFunc("/admin/readonly", exigirAdmin(tokenAdmin, alterarModoLeitura)).Methods(http.MethodPost)
+	}
+
+	servidor := &http.Server{
+		Addr:    enderecoEscuta(),
+		Handler: requestIDMiddleware(descompactarCorpoMiddleware(registrarCorposMiddleware(httpsMiddleware(removerPrefixoMiddleware(inicializacaoMiddleware(limiteQueryMiddleware(corsMiddleware(r)))))))),
+	}
+	encerrado := encerrarAoSinal(servidor)
 
-	fmt.Println("Servidor em execução na porta 3333")
-	err := http.ListenAndServe(":3333", r)
+	// A porta é aberta antes da conexão com o banco e das migrações; até lá,
+	// inicializacaoMiddleware responde 503 com Retry-After.
+	ouvinte, err := net.Listen("tcp", servidor.Addr)
 	if err != nil {
 		fmt.Println("Erro ao iniciar o servidor:", err)
+		return
+	}
+	fmt.Println("Servidor em execução em", ouvinte.Addr())
+
+	erroServidor := make(chan error, 1)
+	go func() { erroServidor <- servidor.Serve(ouvinte) }()
+
+	configurarDB()
+	defer dbConn.Close()
+	if dbLeitura != dbConn {
+		defer dbLeitura.Close()
+	}
+
+	repositorio = limitarConcorrencia(NewMySQLPessoaRepository(dbConn, dbLeitura))
+
+	iniciarPprof()
+	iniciarWebhooks()
+	iniciarCache()
+	iniciarGuardaSubmissoes()
+
+	ctxMonitor, pararMonitor := context.WithCancel(context.Background())
+	defer pararMonitor()
+	iniciarMonitorBanco(ctxMonitor)
+
+	registrarResumoConfiguracao(ouvinte.Addr().String())
+	inicializado.Store(true)
+
+	if err := <-erroServidor; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println("Erro no servidor:", err)
+		return
 	}
+	<-encerrado
 }
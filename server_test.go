@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// respostaDescartada é um http.ResponseWriter que descarta o corpo, para que
+// os benchmarks meçam só a codificação.
+type respostaDescartada struct {
+	cabecalho http.Header
+}
+
+func (d *respostaDescartada) Header() http.Header         { return d.cabecalho }
+func (d *respostaDescartada) Write(b []byte) (int, error) { return len(b), nil }
+func (d *respostaDescartada) WriteHeader(int)             {}
+
+// listaBenchmark monta uma listagem típica de n pessoas.
+func listaBenchmark(n int) []Pessoa {
+	criadoEm := &Instante{Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	pessoas := make([]Pessoa, n)
+	for i := range pessoas {
+		pessoas[i] = Pessoa{ID: i + 1, Nome: "Pessoa " + strconv.Itoa(i+1), CriadoEm: criadoEm}
+	}
+	return pessoas
+}
+
+// BenchmarkResponderJSON mede responderJSON, que codifica num buffer de
+// buffersJSON. Compare as alocações com BenchmarkResponderJSONSemPool.
+func BenchmarkResponderJSON(b *testing.B) {
+	pessoas := listaBenchmark(100)
+	r := httptest.NewRequest(http.MethodGet, "/pessoas", nil)
+	w := &respostaDescartada{cabecalho: make(http.Header)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		responderJSON(w, r, http.StatusOK, pessoas)
+	}
+}
+
+// BenchmarkResponderJSONSemPool é a referência: a mesma resposta codificada
+// num buffer novo a cada chamada, como antes de buffersJSON.
+func BenchmarkResponderJSONSemPool(b *testing.B) {
+	pessoas := listaBenchmark(100)
+	w := &respostaDescartada{cabecalho: make(http.Header)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(pessoas); err != nil {
+			b.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
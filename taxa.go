@@ -0,0 +1,201 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// janelaTaxa conta as requisições de uma chave na janela corrente.
+type janelaTaxa struct {
+	inicio time.Time
+	total  int
+}
+
+// limitadorTaxa aplica limites de requisições por janela fixa, por chave
+// (IP + rota).
+type limitadorTaxa struct {
+	mu      sync.Mutex
+	janela  time.Duration
+	janelas map[string]*janelaTaxa
+}
+
+// permitir contabiliza uma requisição da chave e informa se ela cabe no limite.
+// Quando não cabe, devolve também quanto falta para a janela reiniciar.
+func (l *limitadorTaxa) permitir(chave string, limite int, agora time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	j, ok := l.janelas[chave]
+	if !ok || agora.Sub(j.inicio) >= l.janela {
+		j = &janelaTaxa{inicio: agora}
+		l.janelas[chave] = j
+	}
+	if j.total >= limite {
+		return false, j.inicio.Add(l.janela).Sub(agora)
+	}
+	j.total++
+	return true, 0
+}
+
+// limpar descarta as janelas já encerradas, para que o mapa não cresça com
+// clientes que deixaram de chamar.
+func (l *limitadorTaxa) limpar(agora time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for chave, j := range l.janelas {
+		if agora.Sub(j.inicio) >= l.janela {
+			delete(l.janelas, chave)
+		}
+	}
+}
+
+// lerLimitesRotas interpreta RATE_LIMIT_ROUTES, uma lista de pares
+// "[MÉTODO ]template=limite", como "POST /pessoas=30,GET /pessoas/{id}=600".
+// Sem método, o limite vale para todos os métodos da rota.
+func lerLimitesRotas() map[string]int {
+	limites := make(map[string]int)
+	for _, item := range lerEnvLista("RATE_LIMIT_ROUTES") {
+		rota, valor, ok := strings.Cut(item, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(valor))
+		if !ok || err != nil || n < 0 {
+			log.Fatalf("Valor inválido em RATE_LIMIT_ROUTES: %q", item)
+		}
+		limites[strings.Join(strings.Fields(rota), " ")] = n
+	}
+	return limites
+}
+
+// ipCliente devolve o IP de origem da conexão, sem a porta.
+func ipCliente(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limiteTaxaMiddleware limita as requisições por IP e rota dentro de cada
+// RATE_LIMIT_WINDOW (1m). O limite padrão vem de RATE_LIMIT (0 desativa) e pode
+// ser ajustado por rota em RATE_LIMIT_ROUTES, de modo que as escritas recebam
+// um limite menor que as leituras. Acima do limite a resposta é 429 com
+// Retry-After. Precisa rodar como middleware do mux para conhecer a rota; como o
+// mux reaplica os middlewares a cada requisição, o limitador é criado aqui, uma
+// única vez, e compartilhado pelo middleware devolvido.
+func limiteTaxaMiddleware() mux.MiddlewareFunc {
+	padrao := lerEnvInt("RATE_LIMIT", 0)
+	limites := lerLimitesRotas()
+	if padrao <= 0 && len(limites) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	limitador := &limitadorTaxa{
+		janela:  lerEnvDuracao("RATE_LIMIT_WINDOW", time.Minute),
+		janelas: make(map[string]*janelaTaxa),
+	}
+	go func() {
+		for agora := range time.Tick(limitador.janela) {
+			limitador.limpar(agora)
+		}
+	}()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rota := templateRota(r)
+			limite, ok := limites[r.Method+" "+rota]
+			if !ok {
+				limite, ok = limites[rota]
+			}
+			if !ok {
+				limite = padrao
+			}
+			if limite <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			permitido, espera := limitador.permitir(ipCliente(r)+" "+r.Method+" "+rota, limite, time.Now())
+			if !permitido {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(espera.Seconds()))))
+				responderErro(w, r, http.StatusTooManyRequests, mensagem(r, "limite_taxa"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// emAndamentoIP conta as requisições em andamento de cada IP.
+type emAndamentoIP struct {
+	contadores sync.Map // IP -> *atomic.Int64
+}
+
+// entrar reserva uma vaga para o ip, devolvendo false quando ele já tem limite
+// requisições em andamento.
+func (e *emAndamentoIP) entrar(ip string, limite int64) (*atomic.Int64, bool) {
+	for {
+		v, _ := e.contadores.LoadOrStore(ip, new(atomic.Int64))
+		contador := v.(*atomic.Int64)
+		n := contador.Add(1)
+		// Um contador que chegou a zero pode ter sido removido entre o Load e o
+		// Add; nesse caso a contagem recomeça no contador novo.
+		if atual, ok := e.contadores.Load(ip); !ok || atual != contador {
+			contador.Add(-1)
+			continue
+		}
+		if n > limite {
+			e.sair(ip, contador)
+			return nil, false
+		}
+		return contador, true
+	}
+}
+
+// sair libera a vaga, removendo o contador do ip quando ele não tem mais
+// requisições em andamento, para que o mapa não cresça com IPs de passagem.
+func (e *emAndamentoIP) sair(ip string, contador *atomic.Int64) {
+	if contador.Add(-1) == 0 {
+		e.contadores.CompareAndDelete(ip, contador)
+	}
+}
+
+// limiteSimultaneasMiddleware limita a MAX_CONCURRENT_PER_IP (20; 0 desliga) as
+// requisições simultâneas de cada IP, respondendo 429, para que um cliente com
+// muitas requisições lentas não ocupe sozinho as goroutines e as conexões com o
+// banco. As rotas de streaming (rotasSemTimeout) ficam de fora, já que cada
+// conexão delas dura indefinidamente.
+func limiteSimultaneasMiddleware() mux.MiddlewareFunc {
+	limite := int64(lerEnvInt("MAX_CONCURRENT_PER_IP", 20))
+	if limite <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	andamento := &emAndamentoIP{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rotasSemTimeout[templateRota(r)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := ipCliente(r)
+			contador, ok := andamento.entrar(ip, limite)
+			if !ok {
+				w.Header().Set("Retry-After", "1")
+				responderErro(w, r, http.StatusTooManyRequests, mensagem(r, "simultaneas_demais"))
+				return
+			}
+			defer andamento.sair(ip, contador)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
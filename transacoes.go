@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+)
+
+// Operações do repositório cujo nível de isolamento pode ser configurado.
+const (
+	transacaoCriacao     = "create"
+	transacaoAtualizacao = "update"
+	transacaoLote        = "bulk"
+	transacaoRemocao     = "delete"
+)
+
+// niveisIsolamento são os valores aceitos em DB_ISOLATION_LEVEL*.
+var niveisIsolamento = map[string]sql.IsolationLevel{
+	"":                 sql.LevelDefault,
+	"read-uncommitted": sql.LevelReadUncommitted,
+	"read-committed":   sql.LevelReadCommitted,
+	"repeatable-read":  sql.LevelRepeatableRead,
+	"serializable":     sql.LevelSerializable,
+}
+
+// lerIsolamento lê os níveis de isolamento das transações. DB_ISOLATION_LEVEL
+// vale para todas as operações e DB_ISOLATION_LEVEL_<OPERAÇÃO> (CREATE, UPDATE,
+// BULK, DELETE) o substitui para uma delas. Vazio mantém o padrão do servidor
+// (REPEATABLE READ no InnoDB).
+//
+// Os compromissos: read-committed reduz bloqueios de intervalo e deadlocks, mas
+// leituras repetidas na mesma transação podem ver dados diferentes; as
+// atualizações e remoções daqui travam as linhas lidas com FOR UPDATE, então
+// continuam corretas. Sem bloqueio de intervalo, porém, um FOR UPDATE sobre um
+// nome inexistente não trava nada, e duas criações concorrentes passariam pela
+// verificação de If-None-Match: *; por isso CreateIfAbsent usa no mínimo
+// repeatable-read (veja iniciarTransacaoSeAusente). serializable torna toda
+// leitura travante, dando a garantia mais forte ao custo de mais espera e
+// deadlocks sob contenção, que o cliente recebe como erro 500 e deve repetir.
+func lerIsolamento() map[string]sql.IsolationLevel {
+	padrao := nivelIsolamento("DB_ISOLATION_LEVEL", sql.LevelDefault)
+
+	niveis := make(map[string]sql.IsolationLevel)
+	for _, operacao := range []string{transacaoCriacao, transacaoAtualizacao, transacaoLote, transacaoRemocao} {
+		niveis[operacao] = nivelIsolamento("DB_ISOLATION_LEVEL_"+strings.ToUpper(operacao), padrao)
+	}
+	return niveis
+}
+
+// nivelIsolamento interpreta a variável de ambiente nome como nível de
+// isolamento.
+func nivelIsolamento(nome string, padrao sql.IsolationLevel) sql.IsolationLevel {
+	valor := strings.ToLower(lerEnv(nome, ""))
+	if valor == "" {
+		return padrao
+	}
+	nivel, ok := niveisIsolamento[valor]
+	if !ok {
+		log.Fatalf("Valor inválido para %s: %q", nome, valor)
+	}
+	return nivel
+}
+
+// confirmarEscrita incrementa a versão de escrita e confirma a transação. O
+// UPDATE trava a linha única de {versao} até o commit, então as transações de
+// escrita recebem versões na ordem em que são confirmadas: quem lê a versão v
+// já enxerga todas as escritas até ela. Fica por último para que a trava dure
+// só até o commit.
+func confirmarEscrita(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, sqlPessoas("UPDATE {versao} SET versao = versao + 1 WHERE id = 1")); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// iniciarTransacaoSeAusente abre a transação de CreateIfAbsent com o nível de
+// DB_ISOLATION_LEVEL_CREATE, elevado a repeatable-read quando ele é mais fraco
+// ou fica a cargo do servidor: só assim o FOR UPDATE trava a lacuna do nome
+// procurado e impede uma inserção concorrente.
+func (repo *MySQLPessoaRepository) iniciarTransacaoSeAusente(ctx context.Context) (*sql.Tx, error) {
+	nivel := repo.isolamento[transacaoCriacao]
+	if nivel != sql.LevelSerializable {
+		nivel = sql.LevelRepeatableRead
+	}
+	return repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: nivel})
+}
+
+// iniciarTransacao abre uma transação com o nível de isolamento configurado
+// para a operação.
+func (repo *MySQLPessoaRepository) iniciarTransacao(ctx context.Context, operacao string) (*sql.Tx, error) {
+	return repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: repo.isolamento[operacao]})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// caixaNome cria a transformação de caixa aplicada por normalizarNome, escolhida
+// por configurarCaixaNome; nil preserva o nome como foi enviado. Cada chamada
+// gera um Caser novo porque ele guarda estado e não pode ser compartilhado
+// entre goroutines.
+var caixaNome func() cases.Caser
+
+// configurarCaixaNome lê NAME_CASE (none, title, upper ou lower). As
+// transformações seguem as regras do português, então "joão da silva" vira
+// "João Da Silva" com title.
+func configurarCaixaNome() error {
+	switch modo := lerEnv("NAME_CASE", "none"); modo {
+	case "none":
+		caixaNome = nil
+	case "title":
+		caixaNome = func() cases.Caser { return cases.Title(language.BrazilianPortuguese) }
+	case "upper":
+		caixaNome = func() cases.Caser { return cases.Upper(language.BrazilianPortuguese) }
+	case "lower":
+		caixaNome = func() cases.Caser { return cases.Lower(language.BrazilianPortuguese) }
+	default:
+		return fmt.Errorf("NAME_CASE inválido: %q", modo)
+	}
+	return nil
+}
+
+// normalizarNome remove espaços supérfluos do nome informado pelo cliente e
+// aplica a caixa configurada em NAME_CASE.
+func normalizarNome(nome string) string {
+	nome = strings.TrimSpace(nome)
+	// Bytes inválidos são preservados para que validarPessoa os recuse, em vez
+	// de serem trocados silenciosamente por U+FFFD pela transformação de caixa.
+	if caixaNome != nil && utf8.ValidString(nome) {
+		caser := caixaNome()
+		nome = caser.String(nome)
+	}
+	return nome
+}
+
+// bytesOutrasColunas é o espaço que as demais colunas da tabela de pessoas
+// ocupam no limite de 65535 bytes por linha do MySQL: id (INT), uuid
+// (CHAR(36) em utf8mb4), criado_em e atualizado_em (DATETIME) e o byte dos
+// nulos. Precisa acompanhar a migração quando uma coluna for adicionada.
+const bytesOutrasColunas = 4 + 36*4 + 5 + 5 + 1
+
+// limiteVarchar é o maior tamanho de nome, em caracteres, que cabe na linha com
+// as outras colunas: 4 bytes por caractere em utf8mb4 e 2 de comprimento.
+const limiteVarchar = (65535 - bytesOutrasColunas - 2) / 4
+
+// tamanhoMaximoNome é o número máximo de caracteres do nome, configurável por
+// MAX_NAME_LENGTH. O mesmo valor dimensiona a coluna na migração, mantendo a
+// validação e o banco de acordo.
+var tamanhoMaximoNome = lerTamanhoMaximoNome()
+
+// lerTamanhoMaximoNome lê MAX_NAME_LENGTH (255 por padrão), que precisa ficar
+// entre 1 e limiteVarchar.
+func lerTamanhoMaximoNome() int {
+	n := lerEnvInt("MAX_NAME_LENGTH", 255)
+	if n < 1 || n > limiteVarchar {
+		log.Fatalf("MAX_NAME_LENGTH deve estar entre 1 e %d: %d", limiteVarchar, n)
+	}
+	return n
+}
+
+// nomesUnicosSemCaixa, configurável por NAME_UNIQUE_CASE_INSENSITIVE (true por
+// padrão), define se "João" e "joão" contam como o mesmo nome nas verificações
+// de duplicidade: If-None-Match: * na criação e a guarda de submissões
+// repetidas. Sem distinção de caixa, depende de uma collation _ci na coluna.
+var nomesUnicosSemCaixa = lerEnvBool("NAME_UNIQUE_CASE_INSENSITIVE", true)
+
+// modoUnicidadeNome descreve o comportamento ativo de nomesUnicosSemCaixa.
+func modoUnicidadeNome() string {
+	if nomesUnicosSemCaixa {
+		return "case_insensitive"
+	}
+	return "case_sensitive"
+}
+
+// chaveUnicidadeNome devolve a forma de nome usada para compará-lo com outros.
+func chaveUnicidadeNome(nome string) string {
+	if nomesUnicosSemCaixa {
+		return strings.ToLower(nome)
+	}
+	return nome
+}
+
+// validarPessoa verifica se os dados da pessoa podem ser gravados. Os handlers
+// respondem com o status de statusErroValidacao quando ela falha.
+func validarPessoa(p Pessoa) error {
+	if !utf8.ValidString(p.Nome) {
+		return novoErro("nome_utf8_invalido")
+	}
+	if strings.ContainsFunc(p.Nome, unicode.IsControl) {
+		return novoErro("nome_caractere_controle")
+	}
+	if p.Nome == "" {
+		return novoErro("nome_obrigatorio")
+	}
+	if utf8.RuneCountInString(p.Nome) > tamanhoMaximoNome {
+		return novoErro("nome_longo", tamanhoMaximoNome)
+	}
+	if termo, ok := termoBloqueado(p.Nome); ok {
+		return novoErro("nome_bloqueado", termo)
+	}
+	return nil
+}
+
+// statusErroValidacao devolve o status HTTP de um erro de validarPessoa: 400
+// quando o conteúdo está malformado (UTF-8 inválido ou caracteres de controle)
+// e 422 quando ele é bem formado mas não atende às regras.
+func statusErroValidacao(err error) int {
+	var localizado *erroLocalizado
+	if errors.As(err, &localizado) {
+		switch localizado.chave {
+		case "nome_utf8_invalido", "nome_caractere_controle":
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusUnprocessableEntity
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// filaWebhook recebe os eventos a entregar em WEBHOOK_URL. Fica nil quando o
+// webhook não está configurado.
+var filaWebhook chan EventoPessoa
+
+// tentativasWebhook é o número de tentativas de entrega de cada evento.
+const tentativasWebhook = 3
+
+// esperaBaseWebhook é a espera antes da segunda tentativa, dobrada a cada nova
+// falha; configurável por WEBHOOK_RETRY_BASE.
+var esperaBaseWebhook = lerEnvDuracao("WEBHOOK_RETRY_BASE", 500*time.Millisecond)
+
+// iniciarWebhooks sobe os workers de entrega quando WEBHOOK_URL está definida.
+// A fila tem tamanho WEBHOOK_QUEUE_SIZE e é consumida por WEBHOOK_WORKERS
+// goroutines, de modo que um destino lento não acumula goroutines.
+func iniciarWebhooks() {
+	destino := lerEnv("WEBHOOK_URL", "")
+	if destino == "" {
+		return
+	}
+
+	filaWebhook = make(chan EventoPessoa, lerEnvInt("WEBHOOK_QUEUE_SIZE", 100))
+	cliente := &http.Client{Timeout: 5 * time.Second}
+
+	for i := 0; i < lerEnvInt("WEBHOOK_WORKERS", 4); i++ {
+		go func() {
+			for evento := range filaWebhook {
+				entregarWebhook(cliente, destino, evento)
+			}
+		}()
+	}
+}
+
+// enfileirarWebhook agenda a entrega do evento sem bloquear. Com a fila cheia o
+// evento é descartado e registrado no log.
+func enfileirarWebhook(evento EventoPessoa) {
+	if filaWebhook == nil {
+		return
+	}
+
+	select {
+	case filaWebhook <- evento:
+	default:
+		metricaWebhookDescartados.Add(1)
+		log.Printf("Fila de webhook cheia; evento %s da pessoa %d descartado", evento.Type, evento.Pessoa.ID)
+	}
+}
+
+// entregarWebhook envia o evento ao destino, tentando novamente em caso de
+// falha de rede ou resposta fora da faixa 2xx. Entre as tentativas a espera
+// cresce exponencialmente, com jitter, para que vários eventos falhando juntos
+// não voltem todos ao mesmo tempo. Um evento que esgota as tentativas vai para
+// o log de dead letters com o corpo completo, permitindo reenviá-lo à mão.
+func entregarWebhook(cliente *http.Client, destino string, evento EventoPessoa) {
+	corpo, err := codificarJSON(evento)
+	if err != nil {
+		log.Printf("Erro ao codificar webhook %s da pessoa %d: %v", evento.Type, evento.Pessoa.ID, err)
+		metricaWebhookFalhas.Add(1)
+		return
+	}
+
+	for tentativa := 1; ; tentativa++ {
+		err = enviarWebhook(cliente, destino, corpo)
+		if err == nil {
+			metricaWebhookEntregues.Add(1)
+			return
+		}
+		if tentativa == tentativasWebhook {
+			break
+		}
+		time.Sleep(esperaWebhook(tentativa))
+	}
+
+	metricaWebhookFalhas.Add(1)
+	log.Printf("Webhook dead letter após %d tentativas (%v): %s", tentativasWebhook, err, corpo)
+}
+
+// esperaWebhook devolve a espera após a tentativa informada: um valor
+// aleatório entre zero e esperaBaseWebhook·2^(tentativa-1) ("full jitter").
+func esperaWebhook(tentativa int) time.Duration {
+	teto := esperaBaseWebhook << (tentativa - 1)
+	if teto <= 0 {
+		return 0
+	}
+	return rand.N(teto)
+}
+
+// enviarWebhook faz uma única tentativa de entrega.
+func enviarWebhook(cliente *http.Client, destino string, corpo []byte) error {
+	resposta, err := cliente.Post(destino, "application/json", bytes.NewReader(corpo))
+	if err != nil {
+		return err
+	}
+	defer resposta.Body.Close()
+
+	if resposta.StatusCode < 200 || resposta.StatusCode > 299 {
+		return fmt.Errorf("status %d", resposta.StatusCode)
+	}
+	return nil
+}